@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
+	"syscall"
 	"time"
 
 	"net"
@@ -18,8 +21,14 @@ import (
 	"github.com/jawher/mow.cli"
 	_ "github.com/joho/godotenv/autoload"
 	"github.com/rcrowley/go-metrics"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 )
 
+// cleanupTimeout is how long the cleanups run once the server has stopped have to finish, such as leaving the
+// kafka consumer group and exporting the spans still batched
+const cleanupTimeout = 5 * time.Second
+
 var httpClient = http.Client{
 	Transport: &http.Transport{
 		MaxIdleConnsPerHost: 128,
@@ -63,6 +72,24 @@ func main() {
 		Desc:   "Duration Get requests should be cached for. e.g. 2h45m would set the max-age value to '7440' seconds",
 		EnvVar: "CACHE_DURATION",
 	})
+	cacheSize := app.Int(cli.IntOpt{
+		Name:   "brand-cache-size",
+		Value:  1000,
+		Desc:   "Most brands kept in the in-memory brand cache, 0 turns the cache off",
+		EnvVar: "BRAND_CACHE_SIZE",
+	})
+	cacheTTL := app.String(cli.StringOpt{
+		Name:   "brand-cache-ttl",
+		Value:  "",
+		Desc:   "How long brands are kept in the in-memory brand cache, defaults to cache-duration",
+		EnvVar: "BRAND_CACHE_TTL",
+	})
+	staleIfError := app.String(cli.StringOpt{
+		Name:   "stale-if-error",
+		Value:  "0s",
+		Desc:   "How long after expiring from the in-memory brand cache a brand is still served when the concepts api or neo4j is failing, 0s turns this off",
+		EnvVar: "STALE_IF_ERROR",
+	})
 	healthcheckInterval := app.String(cli.StringOpt{
 		Name:   "healthcheck-interval",
 		Value:  "30s",
@@ -75,34 +102,343 @@ func main() {
 		Desc:   "Url of public concepts api",
 		EnvVar: "CONCEPTS_API",
 	})
+	backend := app.String(cli.StringOpt{
+		Name:   "backend",
+		Value:  "concepts",
+		Desc:   "Where brands are read from, either concepts (public-concepts-api) or neo4j (neo-url)",
+		EnvVar: "BACKEND",
+	})
+	requestTimeout := app.String(cli.StringOpt{
+		Name:   "request-timeout",
+		Value:  "10s",
+		Desc:   "How long a request can wait on the concepts api or neo4j before it gets a 504, 0s is no limit",
+		EnvVar: "REQUEST_TIMEOUT",
+	})
+	retryMaxAttempts := app.Int(cli.IntOpt{
+		Name:   "retry-max-attempts",
+		Value:  3,
+		Desc:   "Most attempts made at a GET from the concepts api that fails with a network error, 502, 503 or 504",
+		EnvVar: "RETRY_MAX_ATTEMPTS",
+	})
+	retryBudget := app.String(cli.StringOpt{
+		Name:   "retry-budget",
+		Value:  "2s",
+		Desc:   "How long after the first attempt at a GET from the concepts api another attempt can be started",
+		EnvVar: "RETRY_BUDGET",
+	})
+	breakerFailureThreshold := app.Int(cli.IntOpt{
+		Name:   "breaker-failure-threshold",
+		Value:  5,
+		Desc:   "Consecutive upstream failures that open the circuit breaker, 0 turns the breaker off",
+		EnvVar: "BREAKER_FAILURE_THRESHOLD",
+	})
+	breakerSuccessThreshold := app.Int(cli.IntOpt{
+		Name:   "breaker-success-threshold",
+		Value:  1,
+		Desc:   "Consecutive successful requests that close a half-open circuit breaker",
+		EnvVar: "BREAKER_SUCCESS_THRESHOLD",
+	})
+	breakerOpenDuration := app.String(cli.StringOpt{
+		Name:   "breaker-open-duration",
+		Value:  "30s",
+		Desc:   "How long an open circuit breaker fails requests fast before letting one through",
+		EnvVar: "BREAKER_OPEN_DURATION",
+	})
+	concordancesApiUrl := app.String(cli.StringOpt{
+		Name:   "concordancesApiUrl",
+		Value:  "http://localhost:8080",
+		Desc:   "Url of public concordances api",
+		EnvVar: "CONCORDANCES_API",
+	})
+	treeMaxDepth := app.Int(cli.IntOpt{
+		Name:   "tree-max-depth",
+		Value:  5,
+		Desc:   "Deepest brand tree that can be requested from /brands/{uuid}/tree",
+		EnvVar: "TREE_MAX_DEPTH",
+	})
+	treeConcurrency := app.Int(cli.IntOpt{
+		Name:   "tree-concurrency",
+		Value:  8,
+		Desc:   "How many brands of a level of a brand tree are requested from the brand source at the same time",
+		EnvVar: "TREE_CONCURRENCY",
+	})
+	batchMaxSize := app.Int(cli.IntOpt{
+		Name:   "batch-max-size",
+		Value:  500,
+		Desc:   "Most uuids that can be requested at once from /brands/__batch",
+		EnvVar: "BATCH_MAX_SIZE",
+	})
+	batchConcurrency := app.Int(cli.IntOpt{
+		Name:   "batch-concurrency",
+		Value:  8,
+		Desc:   "How many brands of a batch are requested from the brand source at the same time",
+		EnvVar: "BATCH_CONCURRENCY",
+	})
+	searchRefreshInterval := app.String(cli.StringOpt{
+		Name:   "search-refresh-interval",
+		Value:  "10m",
+		Desc:   "How often the brand search index is reloaded from the brand source",
+		EnvVar: "SEARCH_REFRESH_INTERVAL",
+	})
+	searchRefreshTimeout := app.String(cli.StringOpt{
+		Name:   "search-refresh-timeout",
+		Value:  "1m",
+		Desc:   "How long reloading the brand search index can take before it is given up until the next refresh",
+		EnvVar: "SEARCH_REFRESH_TIMEOUT",
+	})
+	tracingExporter := app.String(cli.StringOpt{
+		Name:   "tracing-exporter",
+		Value:  "none",
+		Desc:   "Where OpenTelemetry spans are exported to: otlp, stdout or none",
+		EnvVar: "TRACING_EXPORTER",
+	})
+	otlpEndpoint := app.String(cli.StringOpt{
+		Name:   "otlp-endpoint",
+		Value:  "http://localhost:4318",
+		Desc:   "OTLP/HTTP endpoint of the OpenTelemetry collector spans are exported to with --tracing-exporter=otlp",
+		EnvVar: "OTEL_EXPORTER_OTLP_ENDPOINT",
+	})
+	httpReadTimeout := app.String(cli.StringOpt{
+		Name:   "http-read-timeout",
+		Value:  "10s",
+		Desc:   "How long the server waits to read a whole request, including its body",
+		EnvVar: "HTTP_READ_TIMEOUT",
+	})
+	httpWriteTimeout := app.String(cli.StringOpt{
+		Name:   "http-write-timeout",
+		Value:  "30s",
+		Desc:   "How long the server has to write a response once it has read the request, should exceed request-timeout",
+		EnvVar: "HTTP_WRITE_TIMEOUT",
+	})
+	httpIdleTimeout := app.String(cli.StringOpt{
+		Name:   "http-idle-timeout",
+		Value:  "120s",
+		Desc:   "How long a keep-alive connection is kept open waiting for the next request",
+		EnvVar: "HTTP_IDLE_TIMEOUT",
+	})
+	shutdownGracePeriod := app.String(cli.StringOpt{
+		Name:   "shutdown-grace-period",
+		Value:  "20s",
+		Desc:   "How long in-flight requests have to finish after a SIGTERM before the server stops",
+		EnvVar: "SHUTDOWN_GRACE_PERIOD",
+	})
+	shutdownDrainDelay := app.String(cli.StringOpt{
+		Name:   "shutdown-drain-delay",
+		Value:  "15s",
+		Desc:   "How long after a SIGTERM /__gtg reports not good to go while connections are still accepted, so that the pod is taken out of the service before the server stops listening",
+		EnvVar: "SHUTDOWN_DRAIN_DELAY",
+	})
+	kafkaProxyURL := app.String(cli.StringOpt{
+		Name:   "kafka-proxy-url",
+		Value:  "",
+		Desc:   "Url of the kafka rest proxy concept changes are read through, to evict changed brands from the brand cache. Empty turns eviction off",
+		EnvVar: "KAFKA_PROXY_URL",
+	})
+	conceptChangesTopic := app.String(cli.StringOpt{
+		Name:   "concept-changes-topic",
+		Value:  "ConceptChanges",
+		Desc:   "Kafka topic of concept change notifications, each a json object with the uuid of the changed concept",
+		EnvVar: "CONCEPT_CHANGES_TOPIC",
+	})
+	conceptChangesGroup := app.String(cli.StringOpt{
+		Name:   "concept-changes-group",
+		Value:  "",
+		Desc:   "Kafka consumer group concept changes are read in, which must not be shared with other instances of the service. Defaults to the app system code followed by the host name",
+		EnvVar: "CONCEPT_CHANGES_GROUP",
+	})
 
 	app.Action = func() {
 		log.Infof("public-brands-api will listen on port: %s, connecting to: %s", *port, *neoURL)
-		runServer(*neoURL, *port, *cacheDuration, *env, *conceptsApiUrl)
+		runServer(serverConfig{
+			appSystemCode:           *appSystemCode,
+			env:                     *env,
+			port:                    *port,
+			backend:                 *backend,
+			neoURL:                  *neoURL,
+			conceptsApiUrl:          *conceptsApiUrl,
+			concordancesApiUrl:      *concordancesApiUrl,
+			cacheDuration:           *cacheDuration,
+			cacheSize:               *cacheSize,
+			cacheTTL:                *cacheTTL,
+			staleIfError:            *staleIfError,
+			kafkaProxyURL:           *kafkaProxyURL,
+			conceptChangesTopic:     *conceptChangesTopic,
+			conceptChangesGroup:     *conceptChangesGroup,
+			treeMaxDepth:            *treeMaxDepth,
+			treeConcurrency:         *treeConcurrency,
+			batchMaxSize:            *batchMaxSize,
+			batchConcurrency:        *batchConcurrency,
+			searchRefreshInterval:   *searchRefreshInterval,
+			searchRefreshTimeout:    *searchRefreshTimeout,
+			requestTimeout:          *requestTimeout,
+			retryMaxAttempts:        *retryMaxAttempts,
+			retryBudget:             *retryBudget,
+			breakerFailureThreshold: *breakerFailureThreshold,
+			breakerSuccessThreshold: *breakerSuccessThreshold,
+			breakerOpenDuration:     *breakerOpenDuration,
+			tracingExporter:         *tracingExporter,
+			otlpEndpoint:            *otlpEndpoint,
+			httpReadTimeout:         *httpReadTimeout,
+			httpWriteTimeout:        *httpWriteTimeout,
+			httpIdleTimeout:         *httpIdleTimeout,
+			shutdownDrainDelay:      *shutdownDrainDelay,
+			shutdownGracePeriod:     *shutdownGracePeriod,
+		})
 	}
 
 	log.InitLogger(*appSystemCode, *logLevel)
 	log.WithFields(map[string]interface{}{
 		"HEALTHCHECK_INTERVAL": *healthcheckInterval,
 		"CACHE_DURATION":       *cacheDuration,
+		"BRAND_CACHE_SIZE":     *cacheSize,
+		"BRAND_CACHE_TTL":      *cacheTTL,
+		"STALE_IF_ERROR":       *staleIfError,
 		"NEO_URL":              *neoURL,
+		"BACKEND":              *backend,
+		"TRACING_EXPORTER":     *tracingExporter,
 		"LOG_LEVEL":            *logLevel,
 	}).Info("Starting app with arguments")
 	log.Infof("Application started with args %s", os.Args)
 	app.Run(os.Args)
 }
 
-func runServer(neoURL string, port string, cacheDuration string, env string, conceptsApiUrl string) {
+// serverConfig is how the server was configured on the command line or in the environment
+type serverConfig struct {
+	appSystemCode           string
+	env                     string
+	port                    string
+	backend                 string
+	neoURL                  string
+	conceptsApiUrl          string
+	concordancesApiUrl      string
+	cacheDuration           string
+	cacheSize               int
+	cacheTTL                string
+	staleIfError            string
+	kafkaProxyURL           string
+	conceptChangesTopic     string
+	conceptChangesGroup     string
+	treeMaxDepth            int
+	treeConcurrency         int
+	batchMaxSize            int
+	batchConcurrency        int
+	searchRefreshInterval   string
+	searchRefreshTimeout    string
+	requestTimeout          string
+	retryMaxAttempts        int
+	retryBudget             string
+	breakerFailureThreshold int
+	breakerSuccessThreshold int
+	breakerOpenDuration     string
+	tracingExporter         string
+	otlpEndpoint            string
+	httpReadTimeout         string
+	httpWriteTimeout        string
+	httpIdleTimeout         string
+	shutdownDrainDelay      string
+	shutdownGracePeriod     string
+}
+
+func runServer(config serverConfig) {
 
-	if duration, durationErr := time.ParseDuration(cacheDuration); durationErr != nil {
+	if duration, durationErr := time.ParseDuration(config.cacheDuration); durationErr != nil {
 		log.Fatalf("Failed to parse cache duration string, %v", durationErr)
 	} else {
 		brands.CacheControlHeader = fmt.Sprintf("max-age=%s, public", strconv.FormatFloat(duration.Seconds(), 'f', 0, 64))
 	}
+	brands.MaxTreeDepth = config.treeMaxDepth
+	brands.TreeConcurrency = config.treeConcurrency
+	brands.MaxBatchSize = config.batchMaxSize
+	brands.BatchConcurrency = config.batchConcurrency
+	if timeout, err := time.ParseDuration(config.requestTimeout); err != nil {
+		log.Fatalf("Failed to parse request timeout string, %v", err)
+	} else {
+		brands.RequestTimeout = timeout
+	}
+
+	var cleanups []func(context.Context) error
+	if exporter := newTraceExporter(config.tracingExporter, config.otlpEndpoint); exporter != nil {
+		tracerProvider := brands.NewTracerProvider(exporter, config.appSystemCode)
+		otel.SetTracerProvider(tracerProvider)
+		cleanups = append(cleanups, tracerProvider.Shutdown)
+	}
 
 	servicesRouter := mux.NewRouter()
 
-	handler := brands.NewHandler(&httpClient, conceptsApiUrl)
+	breakerOpenFor, err := time.ParseDuration(config.breakerOpenDuration)
+	if err != nil {
+		log.Fatalf("Failed to parse breaker open duration string, %v", err)
+	}
+
+	retryBudgetDuration, err := time.ParseDuration(config.retryBudget)
+	if err != nil {
+		log.Fatalf("Failed to parse retry budget string, %v", err)
+	}
+
+	var source brands.BrandSource
+	var breaker *brands.CircuitBreakerClient
+	switch config.backend {
+	case "concepts":
+		breaker = brands.NewCircuitBreakerClient(&httpClient, "public-concepts-api", config.breakerFailureThreshold, config.breakerSuccessThreshold, breakerOpenFor)
+		source = brands.NewConceptsAPISource(brands.NewRetryingClient(breaker, config.retryMaxAttempts, retryBudgetDuration, metrics.DefaultRegistry), config.conceptsApiUrl, config.concordancesApiUrl)
+	case "neo4j":
+		breaker = brands.NewCircuitBreakerClient(&httpClient, "neo4j", config.breakerFailureThreshold, config.breakerSuccessThreshold, breakerOpenFor)
+		source = brands.NewNeo4jSource(breaker, config.neoURL)
+	default:
+		log.Fatalf("Unknown backend %s, must be concepts or neo4j", config.backend)
+	}
+	source = brands.NewCoalescingSource(source)
+	if config.cacheSize > 0 {
+		if config.cacheTTL == "" {
+			config.cacheTTL = config.cacheDuration
+		}
+		ttl, err := time.ParseDuration(config.cacheTTL)
+		if err != nil {
+			log.Fatalf("Failed to parse brand cache ttl string, %v", err)
+		}
+		staleWindow, err := time.ParseDuration(config.staleIfError)
+		if err != nil {
+			log.Fatalf("Failed to parse stale-if-error string, %v", err)
+		}
+		cachingSource := brands.NewCachingSource(source, config.cacheSize, ttl, staleWindow, metrics.DefaultRegistry)
+		if config.kafkaProxyURL != "" {
+			group := config.conceptChangesGroup
+			if group == "" {
+				hostname, _ := os.Hostname()
+				group = config.appSystemCode + "-" + hostname
+			}
+			consumer := brands.NewKafkaConsumer(&httpClient, config.kafkaProxyURL, config.conceptChangesTopic, group, time.Second)
+			consumeCtx, stopConsuming := context.WithCancel(context.Background())
+			consumed := make(chan struct{})
+			go func() {
+				defer close(consumed)
+				if err := cachingSource.ConsumeChanges(consumeCtx, consumer); err != nil && consumeCtx.Err() == nil {
+					log.WithError(err).Error("Stopped evicting changed brands from the brand cache")
+				}
+			}()
+			// the consumer deletes its instance on the way out, so that the proxy does not keep it after the pod is gone
+			cleanups = append(cleanups, func(ctx context.Context) error {
+				stopConsuming()
+				select {
+				case <-consumed:
+					return nil
+				case <-ctx.Done():
+					return fmt.Errorf("kafka consumer did not leave consumer group %s: %w", group, ctx.Err())
+				}
+			})
+		}
+		source = cachingSource
+	} else if config.kafkaProxyURL != "" {
+		log.Warn("Concept changes are ignored while the brand cache is turned off")
+	}
+	handler := brands.NewHandler(source, breaker.HealthCheck())
+
+	brands.SearchRefreshTimeout = parseDuration("search refresh timeout", config.searchRefreshTimeout)
+	if interval, err := time.ParseDuration(config.searchRefreshInterval); err != nil {
+		log.Fatalf("Failed to parse search refresh interval string, %v", err)
+	} else {
+		go handler.RefreshSearchIndexEvery(interval)
+	}
 
 	// Healthchecks and standards first
 	healthCheck := fthealth.TimedHealthCheck{
@@ -110,12 +446,13 @@ func runServer(neoURL string, port string, cacheDuration string, env string, con
 			SystemCode:  "public-brand-api",
 			Name:        "PublicBrandsRead Healthcheck",
 			Description: "Checks downstream services health",
-			Checks:      []fthealth.Check{handler.HealthCheck()},
+			Checks:      handler.HealthChecks(),
 		},
 		Timeout: 10 * time.Second,
 	}
 
 	servicesRouter.HandleFunc("/__health", fthealth.Handler(healthCheck))
+	servicesRouter.Handle("/metrics", brands.MetricsHandler)
 
 	// Then API specific ones:
 	handler.RegisterHandlers(servicesRouter)
@@ -124,13 +461,89 @@ func runServer(neoURL string, port string, cacheDuration string, env string, con
 	monitoringRouter = httphandlers.TransactionAwareRequestLoggingHandler(log.Logger(), monitoringRouter)
 	monitoringRouter = httphandlers.HTTPMetricsHandler(metrics.DefaultRegistry, monitoringRouter)
 
-	http.HandleFunc(status.BuildInfoPath, status.BuildInfoHandler)
-	http.HandleFunc(status.BuildInfoPathDW, status.BuildInfoHandler)
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc(status.BuildInfoPath, status.BuildInfoHandler)
+	serveMux.HandleFunc(status.BuildInfoPathDW, status.BuildInfoHandler)
 	servicesRouter.HandleFunc(status.GTGPath, status.NewGoodToGoHandler(handler.GTG))
-	http.Handle("/", monitoringRouter)
+	serveMux.Handle("/", monitoringRouter)
 
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Unable to start server: %v", err)
+	server := &http.Server{
+		Addr:         ":" + config.port,
+		Handler:      serveMux,
+		ReadTimeout:  parseDuration("http read timeout", config.httpReadTimeout),
+		WriteTimeout: parseDuration("http write timeout", config.httpWriteTimeout),
+		IdleTimeout:  parseDuration("http idle timeout", config.httpIdleTimeout),
 	}
+	serveUntilTerminated(server, &handler, parseDuration("shutdown drain delay", config.shutdownDrainDelay), parseDuration("shutdown grace period", config.shutdownGracePeriod), cleanups...)
+}
+
+// serveUntilTerminated serves requests until a SIGTERM or interrupt, then shuts the server down gracefully
+func serveUntilTerminated(server *http.Server, handler *brands.BrandsHandler, drainDelay time.Duration, gracePeriod time.Duration, cleanups ...func(context.Context) error) {
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Unable to start server: %v", err)
+		}
+	}()
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+	sig := <-signals
+	log.Infof("Received %v, draining connections", sig)
+	shutdown(server, handler, drainDelay, gracePeriod, cleanups...)
+	<-stopped
+	log.Info("Server stopped")
+}
 
+// shutdown makes the handler not good to go and keeps serving for the drain delay, so that readiness probes
+// take the pod out of the service before the server stops accepting connections. The requests in flight then
+// have the grace period to finish, after which the cleanups are run in turn.
+func shutdown(server *http.Server, handler *brands.BrandsHandler, drainDelay time.Duration, gracePeriod time.Duration, cleanups ...func(context.Context) error) {
+	handler.Drain()
+	log.Infof("Not good to go, still accepting connections for %v", drainDelay)
+	time.Sleep(drainDelay)
+
+	log.Infof("Stopped accepting connections, waiting up to %v for requests in flight", gracePeriod)
+	ctx, cancel := context.WithTimeout(context.Background(), gracePeriod)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		log.WithError(err).Error("Connections were not drained within the grace period")
+		server.Close()
+	}
+
+	cleanupCtx, cancelCleanup := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancelCleanup()
+	for _, cleanup := range cleanups {
+		if err := cleanup(cleanupCtx); err != nil {
+			log.WithError(err).Warn("Failed to clean up after the server stopped")
+		}
+	}
+}
+
+// newTraceExporter creates the exporter named by the tracing-exporter option, which is nil for none
+func newTraceExporter(name string, otlpEndpoint string) sdktrace.SpanExporter {
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch name {
+	case "otlp":
+		exporter, err = brands.NewOTLPExporter(context.Background(), otlpEndpoint)
+	case "stdout":
+		exporter, err = brands.NewStdoutExporter(os.Stdout)
+	case "none":
+	default:
+		log.Fatalf("Unknown tracing exporter %s, must be otlp, stdout or none", name)
+	}
+	if err != nil {
+		log.Fatalf("Failed to create the %s trace exporter, %v", name, err)
+	}
+	return exporter
+}
+
+func parseDuration(name string, value string) time.Duration {
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Failed to parse %s string, %v", name, err)
+	}
+	return d
 }
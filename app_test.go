@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	log "github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/public-brands-api/v4/brands"
+	status "github.com/Financial-Times/service-status-go/httphandlers"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownIsNotGoodToGoBeforeClosing(t *testing.T) {
+	log.InitLogger("test-service", "debug")
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer upstream.Close()
+
+	handler := brands.NewHandler(brands.NewConceptsAPISource(http.DefaultClient, upstream.URL, upstream.URL))
+	serveMux := http.NewServeMux()
+	serveMux.HandleFunc(status.GTGPath, status.NewGoodToGoHandler(handler.GTG))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := &http.Server{Handler: serveMux}
+	go server.Serve(listener)
+	gtgURL := "http://" + listener.Addr().String() + status.GTGPath
+
+	resp, err := http.Get(gtgURL)
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode, "gtg before shutdown failed: status codes do not match!")
+
+	flushed := false
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		shutdown(server, &handler, 500*time.Millisecond, time.Second, func(context.Context) error {
+			flushed = true
+			return nil
+		})
+	}()
+
+	var codes []int
+	for deadline := time.Now().Add(250 * time.Millisecond); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		resp, err := http.Get(gtgURL)
+		if !assert.NoError(t, err, "gtg during the drain delay failed: connection was refused!") {
+			break
+		}
+		resp.Body.Close()
+		codes = append(codes, resp.StatusCode)
+	}
+	assert.NotEmpty(t, codes)
+	assert.Equal(t, http.StatusServiceUnavailable, codes[len(codes)-1], "gtg during the drain delay failed: status codes do not match!")
+
+	<-stopped
+	_, err = http.Get(gtgURL)
+	assert.Error(t, err, "gtg after shutdown failed: connection was accepted!")
+	assert.True(t, flushed, "shutdown failed: spans were not flushed!")
+}
@@ -0,0 +1,75 @@
+package brands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	logger "github.com/Financial-Times/go-logger"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/gorilla/mux"
+)
+
+// ancestryError reports a brand hierarchy that cannot be walked to its root
+type ancestryError struct {
+	msg string
+}
+
+func (e *ancestryError) Error() string {
+	return e.msg
+}
+
+// GetBrandAncestors returns the parent brands of the brand, nearest first, up to the root brand
+func (h *BrandsHandler) GetBrandAncestors(w http.ResponseWriter, r *http.Request) {
+	UUID := mux.Vars(r)["uuid"]
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", CacheControlHeader)
+
+	brand, ok := h.resolveBrand(w, r, UUID, transID)
+	if !ok {
+		return
+	}
+
+	ancestors, err := h.getAncestors(r.Context(), brand, transID)
+	if err != nil {
+		if ae, ok := err.(*ancestryError); ok {
+			writeProblem(w, r, problemBrokenHierarchy, ae.Error())
+			return
+		}
+		writeSourceError(w, r, err, "failed to return brand ancestors")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(BrandAncestors{Ancestors: ancestors}); err != nil {
+		logger.WithError(err).WithTransactionID(transID).WithUUID(UUID).Error("brand ancestors could not be marshaled")
+	}
+}
+
+// getAncestors follows the parent brand of each brand in turn until it reaches a brand without one.
+// Parents are looked up by uuid so a non canonical parent resolves to its canonical brand.
+func (h *BrandsHandler) getAncestors(ctx context.Context, brand Brand, transID string) ([]Thing, error) {
+	brandUUID := uuidFromID(brand.ID)
+	visited := map[string]bool{brandUUID: true}
+	ancestors := []Thing{}
+
+	for parent := brand.Parent; parent != nil; {
+		parentUUID := uuidFromID(parent.ID)
+		parentBrand, canonicalUUID, found, err := h.source.GetBrand(ctx, parentUUID, transID)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			return nil, &ancestryError{fmt.Sprintf("ancestor %s of brand %s is not a brand or could not be found", parentUUID, brandUUID)}
+		}
+		if visited[canonicalUUID] {
+			return nil, &ancestryError{fmt.Sprintf("ancestors of brand %s contain a cycle at %s", brandUUID, canonicalUUID)}
+		}
+		visited[canonicalUUID] = true
+		ancestors = append(ancestors, parentBrand.Thing)
+		parent = parentBrand.Parent
+	}
+	return ancestors, nil
+}
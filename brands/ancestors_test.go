@@ -0,0 +1,164 @@
+package brands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBrandAncestors(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name           string
+		url            string
+		expectedCode   int
+		expectedLabels []string
+		expectedBody   string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand Ancestors - Nearest parent first up to the root",
+			"/brands/e363dfb8-f6d9-4f2c-beba-5162b334272b/ancestors",
+			200,
+			[]string{"Lex", "FT"},
+			``,
+		},
+		{
+			"Get Brand Ancestors - Root brand has no ancestors",
+			"/brands/" + rootBrandUUID + "/ancestors",
+			200,
+			nil,
+			``,
+		},
+		{
+			"Get Brand Ancestors - Non canonical parent is followed to its canonical brand",
+			"/brands/f0a1b2c3-0000-4000-8000-000000000001/ancestors",
+			200,
+			[]string{"Lex", "FT"},
+			``,
+		},
+		{
+			"Get Brand Ancestors - Cycle results in error",
+			"/brands/f0a1b2c3-0000-4000-8000-000000000002/ancestors",
+			500,
+			nil,
+			problemJSON(problemBrokenHierarchy, "ancestors of brand f0a1b2c3-0000-4000-8000-000000000002 contain a cycle at f0a1b2c3-0000-4000-8000-000000000002", "/brands/f0a1b2c3-0000-4000-8000-000000000002/ancestors"),
+		},
+		{
+			"Get Brand Ancestors - Non brand parent results in error",
+			"/brands/f0a1b2c3-0000-4000-8000-000000000004/ancestors",
+			500,
+			nil,
+			problemJSON(problemBrokenHierarchy, "ancestor f92a4ca4-84f9-11e8-8f42-da24cd01f044 of brand f0a1b2c3-0000-4000-8000-000000000004 is not a brand or could not be found", "/brands/f0a1b2c3-0000-4000-8000-000000000004/ancestors"),
+		},
+		{
+			"Get Brand Ancestors - Given UUID was not canonical",
+			"/brands/f0a1b2c3-0000-4000-8000-000000000005/ancestors",
+			301,
+			nil,
+			``,
+		},
+		{
+			"Get Brand Ancestors - Invalid UUID results in error",
+			"/brands/1234/ancestors",
+			400,
+			nil,
+			problemJSON(problemInvalidUUID, "uuid '1234' is either missing or invalid", "/brands/1234/ancestors"),
+		},
+	}
+
+	responses := map[string]string{}
+	for path, body := range brandTreeAsConcepts {
+		responses[path] = body
+	}
+	for path, body := range brandAncestorsAsConcepts {
+		responses[path] = body
+	}
+	mockClient := mockRoutedHTTPClient{responses: responses}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "http://localhost:8080", "http://localhost:8080"))
+	bh.RegisterHandlers(router)
+
+	for _, test := range testCases {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.url, nil)
+		req.Header.Set("X-Request-Id", testTransactionID)
+		req.RequestURI = test.url
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		if rr.Code != 200 {
+			assert.Equal(t, test.expectedBody, rr.Body.String(), test.name+" failed: status body does not match!")
+			continue
+		}
+		ancestors := BrandAncestors{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &ancestors), test.name+" failed: body is not a list of ancestors!")
+		assert.Equal(t, test.expectedLabels, labelsOf(ancestors.Ancestors), test.name+" failed: ancestors do not match!")
+	}
+}
+
+var brandAncestorsAsConcepts = map[string]string{
+	// child of a non canonical uuid for Lex
+	"/concepts/f0a1b2c3-0000-4000-8000-000000000001": `{
+		"id": "http://www.ft.com/thing/f0a1b2c3-0000-4000-8000-000000000001",
+		"apiUrl": "http://api.ft.com/concepts/f0a1b2c3-0000-4000-8000-000000000001",
+		"type": "http://www.ft.com/ontology/product/Brand",
+		"prefLabel": "Lex Podcast",
+		"broaderConcepts": [
+			{"concept": {"id": "http://www.ft.com/thing/f0a1b2c3-0000-4000-8000-000000000099", "apiUrl": "http://api.ft.com/concepts/f0a1b2c3-0000-4000-8000-000000000099", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "Old Lex"}}
+		]
+	}`,
+	"/concepts/f0a1b2c3-0000-4000-8000-000000000099": `{
+		"id": "http://www.ft.com/thing/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+		"apiUrl": "http://api.ft.com/concepts/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+		"type": "http://www.ft.com/ontology/product/Brand",
+		"prefLabel": "Lex",
+		"broaderConcepts": [
+			{"concept": {"id": "http://www.ft.com/thing/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54", "apiUrl": "http://api.ft.com/concepts/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "FT"}}
+		]
+	}`,
+	// two brands that are each other's parent
+	"/concepts/f0a1b2c3-0000-4000-8000-000000000002": `{
+		"id": "http://www.ft.com/thing/f0a1b2c3-0000-4000-8000-000000000002",
+		"apiUrl": "http://api.ft.com/concepts/f0a1b2c3-0000-4000-8000-000000000002",
+		"type": "http://www.ft.com/ontology/product/Brand",
+		"prefLabel": "Ouroboros",
+		"broaderConcepts": [
+			{"concept": {"id": "http://www.ft.com/thing/f0a1b2c3-0000-4000-8000-000000000003", "apiUrl": "http://api.ft.com/concepts/f0a1b2c3-0000-4000-8000-000000000003", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "Tail"}}
+		]
+	}`,
+	"/concepts/f0a1b2c3-0000-4000-8000-000000000003": `{
+		"id": "http://www.ft.com/thing/f0a1b2c3-0000-4000-8000-000000000003",
+		"apiUrl": "http://api.ft.com/concepts/f0a1b2c3-0000-4000-8000-000000000003",
+		"type": "http://www.ft.com/ontology/product/Brand",
+		"prefLabel": "Tail",
+		"broaderConcepts": [
+			{"concept": {"id": "http://www.ft.com/thing/f0a1b2c3-0000-4000-8000-000000000002", "apiUrl": "http://api.ft.com/concepts/f0a1b2c3-0000-4000-8000-000000000002", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "Ouroboros"}}
+		]
+	}`,
+	// a brand whose parent uuid resolves to a person
+	"/concepts/f0a1b2c3-0000-4000-8000-000000000004": `{
+		"id": "http://www.ft.com/thing/f0a1b2c3-0000-4000-8000-000000000004",
+		"apiUrl": "http://api.ft.com/concepts/f0a1b2c3-0000-4000-8000-000000000004",
+		"type": "http://www.ft.com/ontology/product/Brand",
+		"prefLabel": "Misfiled",
+		"broaderConcepts": [
+			{"concept": {"id": "http://www.ft.com/thing/f92a4ca4-84f9-11e8-8f42-da24cd01f044", "apiUrl": "http://api.ft.com/concepts/f92a4ca4-84f9-11e8-8f42-da24cd01f044", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "Not a brand"}}
+		]
+	}`,
+	"/concepts/f92a4ca4-84f9-11e8-8f42-da24cd01f044": getPersonAsConcept,
+	// a non canonical uuid for Lex
+	"/concepts/f0a1b2c3-0000-4000-8000-000000000005": `{
+		"id": "http://www.ft.com/thing/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+		"apiUrl": "http://api.ft.com/concepts/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+		"type": "http://www.ft.com/ontology/product/Brand",
+		"prefLabel": "Lex"
+	}`,
+}
@@ -0,0 +1,134 @@
+package brands
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync"
+
+	logger "github.com/Financial-Times/go-logger"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+const (
+	batchStatusFound    = "found"
+	batchStatusRedirect = "redirect"
+	batchStatusNotFound = "notFound"
+	batchStatusInvalid  = "invalid"
+	batchStatusError    = "error"
+)
+
+// batchBytesPerUUID is the room allowed in a batch request body for each uuid, which is 38 bytes quoted and
+// followed by a comma, with some to spare for whitespace
+const batchBytesPerUUID = 64
+
+// batchBodyOverhead is the room allowed in a batch request body for everything but its uuids
+const batchBodyOverhead = 1024
+
+// batchUUIDMatcher only matches a whole uuid. Unlike the uuids of routes, those in the body can contain
+// anything, such as a path that ends in a uuid, and are pasted into the url of the upstream request.
+var batchUUIDMatcher = regexp.MustCompile("^" + validUUID)
+
+// GetBrandBatch looks up every uuid in the request body and returns the outcome for each of them keyed by uuid.
+// Non canonical uuids are reported as a redirect to their canonical uuid rather than served as a 301.
+func (h *BrandsHandler) GetBrandBatch(w http.ResponseWriter, r *http.Request) {
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+	w.Header().Set("Content-Type", "application/json")
+
+	version, err := requestedVersion(r)
+	if err != nil {
+		writeProblem(w, r, problemInvalidRequest, err.Error())
+		return
+	}
+
+	batch := BatchBrandRequest{}
+	maxBodySize := int64(MaxBatchSize*batchBytesPerUUID + batchBodyOverhead)
+	err = json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBodySize)).Decode(&batch)
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		writeProblem(w, r, problemRequestTooLarge, fmt.Sprintf("request body must be at most %d bytes", maxBodySize))
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, problemInvalidRequest, "request body must be a json object with a list of uuids")
+		return
+	}
+	if len(batch.UUIDs) == 0 || len(batch.UUIDs) > MaxBatchSize {
+		writeProblem(w, r, problemInvalidRequest, fmt.Sprintf("between 1 and %d uuids must be requested", MaxBatchSize))
+		return
+	}
+
+	query := r.URL.Query()
+	show := func(brand Brand) Brand {
+		return brand.withOptionalFields(query.Get("showAliases") == "true", query.Get("showAlternativeIdentifiers") == "true").forVersion(version)
+	}
+	results := h.getBrandBatch(r.Context(), batch.UUIDs, show, transID)
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(results); err != nil {
+		logger.WithError(err).WithTransactionID(transID).Error("brand batch could not be marshaled")
+	}
+}
+
+// getBrandBatch requests the brands of the batch from the concepts api using at most BatchConcurrency requests at a time.
+// Each brand found is passed through show to shape it for the response.
+func (h *BrandsHandler) getBrandBatch(ctx context.Context, uuids []string, show func(Brand) Brand, transID string) map[string]BatchBrandResult {
+	results := make(map[string]BatchBrandResult, len(uuids))
+	var pending []string
+	for _, UUID := range uuids {
+		if _, seen := results[UUID]; seen {
+			continue
+		}
+		if !batchUUIDMatcher.MatchString(UUID) {
+			results[UUID] = BatchBrandResult{Status: batchStatusInvalid}
+			continue
+		}
+		results[UUID] = BatchBrandResult{}
+		pending = append(pending, UUID)
+	}
+
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan string)
+	workers := BatchConcurrency
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for UUID := range jobs {
+				result := h.getBatchResult(ctx, UUID, show, transID)
+				mutex.Lock()
+				results[UUID] = result
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for _, UUID := range pending {
+		jobs <- UUID
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func (h *BrandsHandler) getBatchResult(ctx context.Context, UUID string, show func(Brand) Brand, transID string) BatchBrandResult {
+	brand, canonicalUUID, found, err := h.source.GetBrand(ctx, UUID, transID)
+	if err != nil {
+		return BatchBrandResult{Status: batchStatusError}
+	}
+	if !found {
+		return BatchBrandResult{Status: batchStatusNotFound}
+	}
+	if canonicalUUID != "" && canonicalUUID != UUID {
+		return BatchBrandResult{Status: batchStatusRedirect, CanonicalUUID: canonicalUUID}
+	}
+	brand = show(brand)
+	return BatchBrandResult{Status: batchStatusFound, Brand: &brand}
+}
@@ -0,0 +1,127 @@
+package brands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBrandBatch(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	responses := map[string]string{}
+	for path, body := range brandTreeAsConcepts {
+		responses[path] = body
+	}
+	for path, body := range brandAncestorsAsConcepts {
+		responses[path] = body
+	}
+	mockClient := mockRoutedHTTPClient{responses: responses}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "http://localhost:8080", "http://localhost:8080"))
+	bh.RegisterHandlers(router)
+
+	body := `{"uuids": [
+		"2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+		"89d15f70-640d-11e4-9803-0800200c9a66",
+		"2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+		"f0a1b2c3-0000-4000-8000-000000000005",
+		"f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+		"99999999-1f0c-11e4-b0cb-b2227cce2b54",
+		"1234",
+		"../../admin/secret?x=9636919c-838d-11e8-8f42-da24cd01f044"
+	]}`
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("POST", "/brands/__batch", strings.NewReader(body))
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, 200, rr.Code)
+	results := map[string]BatchBrandResult{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &results))
+	assert.Len(t, results, 7)
+
+	assert.Equal(t, batchStatusFound, results["2d3e16e0-61cb-4322-8aff-3b01c59f4daa"].Status)
+	assert.Equal(t, "Lex", results["2d3e16e0-61cb-4322-8aff-3b01c59f4daa"].Brand.PrefLabel)
+	assert.Equal(t, batchStatusFound, results["89d15f70-640d-11e4-9803-0800200c9a66"].Status)
+	assert.Equal(t, "Alphaville", results["89d15f70-640d-11e4-9803-0800200c9a66"].Brand.PrefLabel)
+	assert.Equal(t, BatchBrandResult{Status: batchStatusRedirect, CanonicalUUID: "2d3e16e0-61cb-4322-8aff-3b01c59f4daa"}, results["f0a1b2c3-0000-4000-8000-000000000005"])
+	assert.Equal(t, BatchBrandResult{Status: batchStatusNotFound}, results["f92a4ca4-84f9-11e8-8f42-da24cd01f044"])
+	assert.Equal(t, BatchBrandResult{Status: batchStatusNotFound}, results["99999999-1f0c-11e4-b0cb-b2227cce2b54"])
+	assert.Equal(t, BatchBrandResult{Status: batchStatusInvalid}, results["1234"])
+	assert.Equal(t, BatchBrandResult{Status: batchStatusInvalid}, results["../../admin/secret?x=9636919c-838d-11e8-8f42-da24cd01f044"], "a path ending in a uuid should not be requested")
+}
+
+func TestGetBrandBatchRejectsBadRequests(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	MaxBatchSize = 2
+	defer func() { MaxBatchSize = 500 }()
+
+	type testCase struct {
+		name         string
+		method       string
+		body         string
+		expectedCode int
+		expectedBody string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand Batch - Invalid json results in error",
+			"POST",
+			`{`,
+			400,
+			problemJSON(problemInvalidRequest, "request body must be a json object with a list of uuids", "/brands/__batch"),
+		},
+		{
+			"Get Brand Batch - Empty batch results in error",
+			"POST",
+			`{"uuids": []}`,
+			400,
+			problemJSON(problemInvalidRequest, "between 1 and 2 uuids must be requested", "/brands/__batch"),
+		},
+		{
+			"Get Brand Batch - Batch over the maximum size results in error",
+			"POST",
+			`{"uuids": ["2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "89d15f70-640d-11e4-9803-0800200c9a66", "dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54"]}`,
+			400,
+			problemJSON(problemInvalidRequest, "between 1 and 2 uuids must be requested", "/brands/__batch"),
+		},
+		{
+			"Get Brand Batch - Body over the maximum size is not read",
+			"POST",
+			`{"uuids": ["2d3e16e0-61cb-4322-8aff-3b01c59f4daa"]` + strings.Repeat(" ", 2*batchBytesPerUUID+batchBodyOverhead) + `}`,
+			413,
+			problemJSON(problemRequestTooLarge, "request body must be at most 1152 bytes", "/brands/__batch"),
+		},
+		{
+			"Get Brand Batch - Only POST is allowed",
+			"GET",
+			``,
+			405,
+			``,
+		},
+	}
+
+	mockClient := mockRoutedHTTPClient{responses: brandTreeAsConcepts}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "http://localhost:8080", "http://localhost:8080"))
+	bh.RegisterHandlers(router)
+
+	for _, test := range testCases {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(test.method, "/brands/__batch", strings.NewReader(test.body))
+		req.Header.Set("X-Request-Id", testTransactionID)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		if test.expectedBody != "" {
+			assert.Equal(t, test.expectedBody, rr.Body.String(), test.name+" failed: status body does not match!")
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package brands
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
+	logger "github.com/Financial-Times/go-logger"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// CircuitOpenError is returned instead of making a request while the circuit breaker is open
+type CircuitOpenError struct {
+	Name       string
+	RetryAfter time.Duration
+}
+
+func (e *CircuitOpenError) Error() string {
+	return fmt.Sprintf("circuit breaker to %s is open, retry after %s", e.Name, e.RetryAfter)
+}
+
+// retryAfterSeconds is RetryAfter rounded up to whole seconds for a Retry-After header
+func (e *CircuitOpenError) retryAfterSeconds() int {
+	return int(math.Max(1, math.Ceil(e.RetryAfter.Seconds())))
+}
+
+// CircuitBreakerClient stops making requests to an upstream that keeps failing. After FailureThreshold
+// consecutive failures the breaker opens and requests fail fast with a CircuitOpenError for OpenDuration.
+// It then half-opens and lets one request through at a time, closing again after SuccessThreshold consecutive
+// successes or opening again on the first failure. Errors and 5xx responses are failures.
+// A FailureThreshold of 0 never opens the breaker.
+type CircuitBreakerClient struct {
+	client           httpClient
+	name             string
+	failureThreshold int
+	successThreshold int
+	openDuration     time.Duration
+	now              func() time.Time
+
+	sync.Mutex
+	state     breakerState
+	failures  int
+	successes int
+	openedAt  time.Time
+	probing   bool
+}
+
+func NewCircuitBreakerClient(client httpClient, name string, failureThreshold int, successThreshold int, openDuration time.Duration) *CircuitBreakerClient {
+	return &CircuitBreakerClient{
+		client:           client,
+		name:             name,
+		failureThreshold: failureThreshold,
+		successThreshold: successThreshold,
+		openDuration:     openDuration,
+		now:              time.Now,
+	}
+}
+
+// Unwrap returns the client requests are made with, for requests that should bypass the breaker
+func (c *CircuitBreakerClient) Unwrap() httpClient {
+	return c.client
+}
+
+// Checker fails unless the breaker is closed
+func (c *CircuitBreakerClient) Checker() (string, error) {
+	c.Lock()
+	state := c.currentState()
+	c.Unlock()
+	if state != breakerClosed {
+		return "", fmt.Errorf("circuit breaker to %s is %s", c.name, state)
+	}
+	return fmt.Sprintf("circuit breaker to %s is closed", c.name), nil
+}
+
+func (c *CircuitBreakerClient) HealthCheck() fthealth.Check {
+	return fthealth.Check{
+		ID:               c.name + "-circuit-breaker-check",
+		BusinessImpact:   "Unable to respond to Public Brands api requests",
+		Name:             "Check the circuit breaker to " + c.name,
+		PanicGuide:       "https://runbooks.in.ft.com/public-brands-api",
+		Severity:         2,
+		TechnicalSummary: "The circuit breaker to " + c.name + " opens after consecutive failures, and requests for brands fail fast with a 503 until it closes again.",
+		Checker:          c.Checker,
+	}
+}
+
+func (c *CircuitBreakerClient) Do(req *http.Request) (*http.Response, error) {
+	if err := c.allow(); err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if errors.Is(err, context.Canceled) {
+		// the client went away, which says nothing about the upstream
+		c.release()
+		return resp, err
+	}
+	c.record(err == nil && resp.StatusCode < http.StatusInternalServerError)
+	return resp, err
+}
+
+// currentState half-opens the breaker once it has been open for long enough
+func (c *CircuitBreakerClient) currentState() breakerState {
+	if c.state == breakerOpen && !c.now().Before(c.openedAt.Add(c.openDuration)) {
+		c.transition(breakerHalfOpen)
+		c.successes = 0
+		c.probing = false
+	}
+	return c.state
+}
+
+func (c *CircuitBreakerClient) allow() error {
+	c.Lock()
+	defer c.Unlock()
+	switch c.currentState() {
+	case breakerOpen:
+		return &CircuitOpenError{Name: c.name, RetryAfter: c.openedAt.Add(c.openDuration).Sub(c.now())}
+	case breakerHalfOpen:
+		if c.probing {
+			return &CircuitOpenError{Name: c.name, RetryAfter: time.Second}
+		}
+		c.probing = true
+	}
+	return nil
+}
+
+// release lets another request through a half-open breaker without recording an outcome
+func (c *CircuitBreakerClient) release() {
+	c.Lock()
+	defer c.Unlock()
+	c.probing = false
+}
+
+func (c *CircuitBreakerClient) record(success bool) {
+	c.Lock()
+	defer c.Unlock()
+	switch c.state {
+	case breakerHalfOpen:
+		c.probing = false
+		if !success {
+			c.open()
+			return
+		}
+		c.successes++
+		if c.successes >= c.successThreshold {
+			c.transition(breakerClosed)
+			c.failures = 0
+		}
+	case breakerClosed:
+		if success {
+			c.failures = 0
+			return
+		}
+		c.failures++
+		if c.failureThreshold > 0 && c.failures >= c.failureThreshold {
+			c.open()
+		}
+	}
+}
+
+func (c *CircuitBreakerClient) open() {
+	c.transition(breakerOpen)
+	c.openedAt = c.now()
+}
+
+func (c *CircuitBreakerClient) transition(state breakerState) {
+	c.state = state
+	logger.Warnf("circuit breaker to %s is %s", c.name, state)
+}
@@ -0,0 +1,156 @@
+package brands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/service-status-go/gtg"
+	"github.com/gorilla/mux"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCircuitBreakerClient(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name             string
+		upstream         []int
+		advance          time.Duration
+		expectedState    breakerState
+		expectedUpstream int
+	}
+
+	// an upstream status of 0 is a network error
+	testCases := []testCase{
+		{
+			"Circuit Breaker - Stays closed below the failure threshold",
+			[]int{500, 500, 200, 500, 500},
+			0,
+			breakerClosed,
+			5,
+		},
+		{
+			"Circuit Breaker - Opens at the failure threshold",
+			[]int{500, 0, 503},
+			0,
+			breakerOpen,
+			3,
+		},
+		{
+			"Circuit Breaker - Fails fast while open",
+			[]int{500, 500, 500, 200, 200},
+			0,
+			breakerOpen,
+			3,
+		},
+		{
+			"Circuit Breaker - Not found is not a failure",
+			[]int{404, 404, 404},
+			0,
+			breakerClosed,
+			3,
+		},
+		{
+			"Circuit Breaker - Half-opens after the open duration and closes after enough successes",
+			[]int{500, 500, 500, 200, 200},
+			time.Minute,
+			breakerClosed,
+			5,
+		},
+		{
+			"Circuit Breaker - Opens again when a half-open request fails",
+			[]int{500, 500, 500, 503, 200},
+			time.Minute,
+			breakerOpen,
+			4,
+		},
+	}
+
+	for _, test := range testCases {
+		upstream := &scriptedHTTPClient{statuses: test.upstream}
+		breaker := NewCircuitBreakerClient(upstream, "public-concepts-api", 3, 2, 30*time.Second)
+		now := time.Now()
+		breaker.now = func() time.Time { return now }
+
+		for i := range test.upstream {
+			if i == 3 {
+				now = now.Add(test.advance)
+			}
+			req, _ := http.NewRequest("GET", "http://localhost:8080/concepts", nil)
+			breaker.Do(req)
+		}
+
+		assert.Equal(t, test.expectedState, breaker.state, test.name+" failed: states do not match!")
+		assert.Equal(t, test.expectedUpstream, upstream.calls, test.name+" failed: upstream calls do not match!")
+	}
+}
+
+func TestCircuitBreakerOpenFailsFast(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	upstream := &scriptedHTTPClient{statuses: []int{0, 200}}
+	breaker := NewCircuitBreakerClient(upstream, "public-concepts-api", 1, 1, 30*time.Second)
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(NewRetryingClient(breaker, 1, time.Second, metrics.NewRegistry()), "http://localhost:8080", "http://localhost:8080"), breaker.HealthCheck())
+	bh.RegisterHandlers(router)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", nil)
+	req.Header.Set("X-Request-Id", testTransactionID)
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, 500, rr.Code)
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, 503, rr.Code)
+	assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+	assert.Equal(t, problemJSON(problemUpstreamUnavailable, "upstream is unavailable, retry later", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa"), rr.Body.String())
+	assert.Equal(t, 1, upstream.calls)
+}
+
+func TestCircuitBreakerHealthCheck(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	upstream := &scriptedHTTPClient{statuses: []int{0, 200, 200}}
+	breaker := NewCircuitBreakerClient(upstream, "public-concepts-api", 1, 1, 30*time.Second)
+	bh := NewHandler(NewConceptsAPISource(NewRetryingClient(breaker, 1, time.Second, metrics.NewRegistry()), "http://localhost:8080", "http://localhost:8080"), breaker.HealthCheck())
+	bh.source.GetBrand(context.Background(), "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "tid_test")
+
+	checks := bh.HealthChecks()
+	assert.Len(t, checks, 2)
+	message, err := checks[0].Checker()
+	assert.NoError(t, err, "upstream check should bypass the open breaker")
+	assert.Equal(t, "Public Concepts API is healthy", message)
+	_, err = checks[1].Checker()
+	assert.EqualError(t, err, "circuit breaker to public-concepts-api is open")
+
+	// a successful health check leaves the breaker open
+	_, _, _, err = bh.source.GetBrand(context.Background(), "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "tid_test")
+	assert.True(t, errors.As(err, new(*CircuitOpenError)))
+	assert.Equal(t, 2, upstream.calls)
+
+	assert.Equal(t, gtg.Status{GoodToGo: false, Message: "circuit breaker to public-concepts-api is open"}, bh.GTG())
+}
+
+// scriptedHTTPClient answers with each of statuses in turn, 0 being a network error
+type scriptedHTTPClient struct {
+	statuses []int
+	calls    int
+}
+
+func (s *scriptedHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	status := s.statuses[s.calls%len(s.statuses)]
+	s.calls++
+	if status == 0 {
+		return nil, errors.New("connection refused")
+	}
+	return &http.Response{Body: ioutil.NopCloser(bytes.NewReader([]byte(`{}`))), StatusCode: status}, nil
+}
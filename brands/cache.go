@@ -0,0 +1,257 @@
+package brands
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	logger "github.com/Financial-Times/go-logger"
+	"github.com/rcrowley/go-metrics"
+)
+
+// CachingSource keeps the brands read from another BrandSource in a bounded in-memory cache, so that a brand
+// is only read again once it has expired or been pushed out by brands that were requested more recently.
+// Expired brands are kept for a further stale window, and are served as stale brands when reading them again fails.
+type CachingSource struct {
+	BrandSource
+	cache   *brandCache
+	hits    metrics.Counter
+	misses  metrics.Counter
+	stale   metrics.Counter
+	evicted metrics.Counter
+}
+
+// NewCachingSource caches up to size brands for ttl and keeps serving them for staleWindow after that if the
+// source is failing, counting hits, misses and stale brands served in the registry
+func NewCachingSource(source BrandSource, size int, ttl time.Duration, staleWindow time.Duration, registry metrics.Registry) *CachingSource {
+	return &CachingSource{
+		BrandSource: source,
+		cache:       newBrandCache(size, ttl, staleWindow),
+		hits:        metrics.GetOrRegisterCounter("brands.cache.hits", registry),
+		misses:      metrics.GetOrRegisterCounter("brands.cache.misses", registry),
+		stale:       metrics.GetOrRegisterCounter("brands.cache.stale", registry),
+		evicted:     metrics.GetOrRegisterCounter("brands.cache.evicted", registry),
+	}
+}
+
+// ServesStale is true when there are cached brands that can still be served stale while the source is failing
+func (s *CachingSource) ServesStale() bool {
+	return s.cache.staleWindow > 0 && s.cache.hasServable()
+}
+
+// GetBrand caches brands and redirects to them by the requested uuid. Brands that were not found are not cached,
+// so that new brands are served as soon as they are published.
+func (s *CachingSource) GetBrand(ctx context.Context, UUID string, transID string) (Brand, string, bool, error) {
+	generation := s.cache.currentGeneration()
+	cached, fresh, ok := s.cache.get(UUID)
+	if ok && fresh {
+		s.hits.Inc(1)
+		logger.WithTransactionID(transID).WithUUID(UUID).Debug("serving brand from cache")
+		return cached.brand, cached.canonicalUUID, true, nil
+	}
+	s.misses.Inc(1)
+
+	brand, canonicalUUID, found, err := s.BrandSource.GetBrand(ctx, UUID, transID)
+	if err != nil && ok {
+		s.stale.Inc(1)
+		logger.WithError(err).WithTransactionID(transID).WithUUID(UUID).Warn("serving stale brand from cache")
+		cached.brand.Stale = true
+		return cached.brand, cached.canonicalUUID, true, nil
+	}
+	if err == nil && found && !s.cache.addUnlessEvictedSince(UUID, cachedBrand{brand: brand, canonicalUUID: canonicalUUID}, generation) {
+		logger.WithTransactionID(transID).WithUUID(UUID).Debug("not caching brand, a concept changed while it was being read")
+	}
+	return brand, canonicalUUID, found, err
+}
+
+// Evict removes the brand with UUID from the cache, under its own and any other uuid it was requested by, along
+// with the cached brands that embed it, which are its parents and children and any other brand related to it.
+// Brands that were being read while it was evicted are not cached, as they may have been read before the change.
+// It returns how many entries were removed.
+func (s *CachingSource) Evict(UUID string) int {
+	evicted := s.cache.evict(UUID)
+	s.evicted.Inc(int64(evicted))
+	return evicted
+}
+
+// ConsumeChanges evicts the brands affected by each concept change from consumer, so that they are read again
+// the next time they are requested, until ctx is done or the consumer fails
+func (s *CachingSource) ConsumeChanges(ctx context.Context, consumer ConceptChangeConsumer) error {
+	return consumer.Consume(ctx, func(change ConceptChange) {
+		evicted := s.Evict(change.UUID)
+		logger.WithTransactionID(change.TransactionID).WithUUID(change.UUID).Debugf("concept changed, evicted %d cached brands", evicted)
+	})
+}
+
+type cachedBrand struct {
+	brand         Brand
+	canonicalUUID string
+}
+
+type brandCacheEntry struct {
+	UUID    string
+	value   cachedBrand
+	expires time.Time
+}
+
+// brandCache is a least recently used cache whose entries expire after ttl, and are then kept as stale entries
+// for staleWindow. Its generation goes up on every eviction.
+type brandCache struct {
+	sync.Mutex
+	generation  uint64
+	size        int
+	ttl         time.Duration
+	staleWindow time.Duration
+	entries     map[string]*list.Element
+	order       *list.List
+	now         func() time.Time
+}
+
+func newBrandCache(size int, ttl time.Duration, staleWindow time.Duration) *brandCache {
+	return &brandCache{
+		size:        size,
+		ttl:         ttl,
+		staleWindow: staleWindow,
+		entries:     map[string]*list.Element{},
+		order:       list.New(),
+		now:         time.Now,
+	}
+}
+
+// hasServable is true when any entry is still fresh or within its stale window
+func (c *brandCache) hasServable() bool {
+	c.Lock()
+	defer c.Unlock()
+	now := c.now()
+	for _, element := range c.entries {
+		if now.Before(element.Value.(*brandCacheEntry).expires.Add(c.staleWindow)) {
+			return true
+		}
+	}
+	return false
+}
+
+// get returns the entry for UUID, and whether it is fresh rather than stale
+func (c *brandCache) get(UUID string) (value cachedBrand, fresh bool, ok bool) {
+	c.Lock()
+	defer c.Unlock()
+	element, ok := c.entries[UUID]
+	if !ok {
+		return cachedBrand{}, false, false
+	}
+	entry := element.Value.(*brandCacheEntry)
+	now := c.now()
+	if !now.Before(entry.expires.Add(c.staleWindow)) {
+		c.order.Remove(element)
+		delete(c.entries, UUID)
+		return cachedBrand{}, false, false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, now.Before(entry.expires), true
+}
+
+func (c *brandCache) currentGeneration() uint64 {
+	c.Lock()
+	defer c.Unlock()
+	return c.generation
+}
+
+// addUnlessEvictedSince adds the entry unless there has been an eviction since generation, in which case the
+// value may have been read before the change that caused it
+func (c *brandCache) addUnlessEvictedSince(UUID string, value cachedBrand, generation uint64) bool {
+	c.Lock()
+	defer c.Unlock()
+	if c.generation != generation {
+		return false
+	}
+	c.addLocked(UUID, value)
+	return true
+}
+
+func (c *brandCache) add(UUID string, value cachedBrand) {
+	c.Lock()
+	defer c.Unlock()
+	c.addLocked(UUID, value)
+}
+
+func (c *brandCache) addLocked(UUID string, value cachedBrand) {
+	if c.size <= 0 {
+		return
+	}
+	expires := c.now().Add(c.ttl)
+	if element, ok := c.entries[UUID]; ok {
+		element.Value = &brandCacheEntry{UUID: UUID, value: value, expires: expires}
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[UUID] = c.order.PushFront(&brandCacheEntry{UUID: UUID, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*brandCacheEntry).UUID)
+	}
+}
+
+// evict removes the entries affected by a change to the brand with UUID and moves on to the next generation,
+// all at once so that no entry can be added in between. It returns how many entries were removed.
+func (c *brandCache) evict(UUID string) int {
+	c.Lock()
+	defer c.Unlock()
+	c.generation++
+	keys := c.affectedBy(UUID)
+	for _, key := range keys {
+		c.order.Remove(c.entries[key])
+		delete(c.entries, key)
+	}
+	return len(keys)
+}
+
+// affectedBy returns the keys of the entries that a change to the brand with UUID makes out of date. That is the
+// brand itself under every uuid it is cached by, the brands it was related to when it was cached, and the brands
+// that are related to it now. The cache must be locked.
+func (c *brandCache) affectedBy(UUID string) []string {
+	changed := map[string]bool{UUID: true}
+	for _, element := range c.entries {
+		entry := element.Value.(*brandCacheEntry)
+		if entry.UUID == UUID || entry.value.canonicalUUID == UUID {
+			changed[entry.value.canonicalUUID] = true
+			for _, related := range relatedUUIDs(entry.value.brand) {
+				changed[related] = true
+			}
+		}
+	}
+
+	var keys []string
+	for key, element := range c.entries {
+		entry := element.Value.(*brandCacheEntry)
+		if changed[key] || changed[entry.value.canonicalUUID] || containsString(relatedUUIDs(entry.value.brand), UUID) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// relatedUUIDs are the uuids of the parent and child brands of a brand
+func relatedUUIDs(brand Brand) []string {
+	var related []string
+	if brand.Parent != nil {
+		related = append(related, uuidFromID(brand.Parent.ID))
+	}
+	for _, parent := range brand.Parents {
+		related = append(related, uuidFromID(parent.ID))
+	}
+	for _, child := range brand.Children {
+		related = append(related, uuidFromID(child.ID))
+	}
+	return related
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
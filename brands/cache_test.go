@@ -0,0 +1,251 @@
+package brands
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
+	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/service-status-go/gtg"
+	"github.com/gorilla/mux"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachingSource(t *testing.T) {
+	type testCase struct {
+		name             string
+		requests         []string
+		advance          time.Duration
+		expectedUpstream int
+		expectedHits     int64
+		expectedMisses   int64
+	}
+
+	testCases := []testCase{
+		{
+			"Caching Source - Repeated requests are served from the cache",
+			[]string{"2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "2d3e16e0-61cb-4322-8aff-3b01c59f4daa"},
+			0,
+			1,
+			1,
+			1,
+		},
+		{
+			"Caching Source - Redirects are cached by the requested uuid",
+			[]string{"5c7592a8-1f0c-11e4-b0cb-b2227cce2b54", "5c7592a8-1f0c-11e4-b0cb-b2227cce2b54"},
+			0,
+			1,
+			1,
+			1,
+		},
+		{
+			"Caching Source - Expired brands are read again",
+			[]string{"2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "2d3e16e0-61cb-4322-8aff-3b01c59f4daa"},
+			time.Minute,
+			2,
+			0,
+			2,
+		},
+		{
+			"Caching Source - Least recently used brands are pushed out",
+			[]string{"2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54", "89d15f70-640d-11e4-9803-0800200c9a66", "2d3e16e0-61cb-4322-8aff-3b01c59f4daa"},
+			0,
+			4,
+			0,
+			4,
+		},
+		{
+			"Caching Source - Brands that are not found are not cached",
+			[]string{"99999999-1f0c-11e4-b0cb-b2227cce2b54", "99999999-1f0c-11e4-b0cb-b2227cce2b54"},
+			0,
+			2,
+			0,
+			2,
+		},
+		{
+			"Caching Source - Errors are not cached",
+			[]string{"00000000-0000-0000-0000-000000000000", "00000000-0000-0000-0000-000000000000"},
+			0,
+			2,
+			0,
+			2,
+		},
+	}
+
+	for _, test := range testCases {
+		upstream := &fakeBrandSource{}
+		registry := metrics.NewRegistry()
+		source := NewCachingSource(upstream, 2, 30*time.Second, 0, registry)
+		now := time.Now()
+		source.cache.now = func() time.Time { return now }
+
+		for _, UUID := range test.requests {
+			source.GetBrand(context.Background(), UUID, "tid_test")
+			now = now.Add(test.advance)
+		}
+
+		assert.Equal(t, test.expectedUpstream, upstream.calls, test.name+" failed: upstream calls do not match!")
+		assert.Equal(t, test.expectedHits, metrics.GetOrRegisterCounter("brands.cache.hits", registry).Count(), test.name+" failed: hits do not match!")
+		assert.Equal(t, test.expectedMisses, metrics.GetOrRegisterCounter("brands.cache.misses", registry).Count(), test.name+" failed: misses do not match!")
+	}
+}
+
+func TestCachingSourceServesCachedRedirect(t *testing.T) {
+	source := NewCachingSource(&fakeBrandSource{}, 10, time.Minute, 0, metrics.NewRegistry())
+	source.GetBrand(context.Background(), "5c7592a8-1f0c-11e4-b0cb-b2227cce2b54", "tid_first")
+
+	brand, canonicalUUID, found, err := source.GetBrand(context.Background(), "5c7592a8-1f0c-11e4-b0cb-b2227cce2b54", "tid_second")
+
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", canonicalUUID)
+	assert.Equal(t, "Lex", brand.PrefLabel)
+}
+
+func TestCachingSourceServesStale(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name            string
+		staleWindow     time.Duration
+		advance         time.Duration
+		expectedCode    int
+		expectedWarning string
+		expectedCache   string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand - Expired brand is served stale while the upstream fails",
+			time.Hour,
+			time.Minute,
+			200,
+			`110 - "Response is Stale"`,
+			"max-age=60, public",
+		},
+		{
+			"Get Brand - Brand past the stale window is not served",
+			time.Hour,
+			2 * time.Hour,
+			500,
+			"",
+			"max-age=1800, public",
+		},
+		{
+			"Get Brand - Stale brands are not served without a stale window",
+			0,
+			time.Minute,
+			500,
+			"",
+			"max-age=1800, public",
+		},
+	}
+
+	CacheControlHeader = "max-age=1800, public"
+	defer func() { CacheControlHeader = "" }()
+
+	for _, test := range testCases {
+		mockClient := mockHTTPClient{resp: getBasicBrandAsConcept, statusCode: 200}
+		source := NewCachingSource(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"), 10, 30*time.Second, test.staleWindow, metrics.NewRegistry())
+		now := time.Now()
+		source.cache.now = func() time.Time { return now }
+		router := mux.NewRouter()
+		bh := NewHandler(source)
+		bh.RegisterHandlers(router)
+
+		req, _ := http.NewRequest("GET", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+		mockClient.err = errors.New("connection refused")
+		now = now.Add(test.advance)
+
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		assert.Equal(t, test.expectedWarning, rr.Header().Get("Warning"), test.name+" failed: warnings do not match!")
+		assert.Equal(t, test.expectedCache, rr.Header().Get("Cache-Control"), test.name+" failed: cache control does not match!")
+	}
+}
+
+func TestGTGWhileServingStale(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	mockClient := mockHTTPClient{err: errors.New("connection refused")}
+	concepts := NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts")
+	now := time.Now()
+	cached := func(staleWindow time.Duration, expired time.Duration) *CachingSource {
+		source := NewCachingSource(concepts, 10, time.Minute, staleWindow, metrics.NewRegistry())
+		source.cache.now = func() time.Time { return now.Add(-time.Minute - expired) }
+		source.cache.add("2d3e16e0-61cb-4322-8aff-3b01c59f4daa", cachedBrand{brand: Brand{Thing: Thing{PrefLabel: "Lex"}}})
+		source.cache.now = func() time.Time { return now }
+		return source
+	}
+
+	type testCase struct {
+		name     string
+		source   *CachingSource
+		expected gtg.Status
+	}
+
+	testCases := []testCase{
+		{
+			"GTG - Failing without a stale window",
+			cached(0, 0),
+			gtg.Status{GoodToGo: false, Message: "connection refused"},
+		},
+		{
+			"GTG - Failing with nothing cached to serve stale",
+			NewCachingSource(concepts, 10, time.Minute, time.Hour, metrics.NewRegistry()),
+			gtg.Status{GoodToGo: false, Message: "connection refused"},
+		},
+		{
+			"GTG - Failing with cached brands past their stale window",
+			cached(time.Hour, 2*time.Hour),
+			gtg.Status{GoodToGo: false, Message: "connection refused"},
+		},
+		{
+			"GTG - Degraded while cached brands can be served stale",
+			cached(time.Hour, time.Minute),
+			gtg.Status{GoodToGo: true, Message: "degraded but serving stale brands: connection refused"},
+		},
+	}
+
+	for _, test := range testCases {
+		handler := NewHandler(test.source)
+		assert.Equal(t, test.expected, handler.GTG(), test.name+" failed: statuses do not match!")
+	}
+}
+
+// fakeBrandSource knows Lex under its canonical and a concorded uuid, fails for the nil uuid and counts every read
+type fakeBrandSource struct {
+	calls int
+}
+
+func (f *fakeBrandSource) GetBrand(ctx context.Context, UUID string, transID string) (Brand, string, bool, error) {
+	f.calls++
+	switch UUID {
+	case "00000000-0000-0000-0000-000000000000":
+		return Brand{}, "", false, errors.New("upstream failed")
+	case "99999999-1f0c-11e4-b0cb-b2227cce2b54":
+		return Brand{}, "", false, nil
+	case "5c7592a8-1f0c-11e4-b0cb-b2227cce2b54":
+		return Brand{Thing: Thing{PrefLabel: "Lex"}}, "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", true, nil
+	}
+	return Brand{Thing: Thing{ID: thingsApiUrl + UUID}}, UUID, true, nil
+}
+
+func (f *fakeBrandSource) GetBrands(ctx context.Context, transID string) ([]Brand, error) {
+	return nil, nil
+}
+
+func (f *fakeBrandSource) GetBrandUUIDByIdentifier(ctx context.Context, authority string, identifierValue string, transID string) (string, bool, error) {
+	return "", false, nil
+}
+
+func (f *fakeBrandSource) HealthCheck() fthealth.Check {
+	return fthealth.Check{}
+}
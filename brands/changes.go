@@ -0,0 +1,104 @@
+package brands
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	logger "github.com/Financial-Times/go-logger"
+)
+
+// ConceptChange is a notification that a concept has been updated
+type ConceptChange struct {
+	UUID          string `json:"uuid"`
+	TransactionID string `json:"transactionId,omitempty"`
+}
+
+// ConceptChangeConsumer delivers concept change notifications to handle, in the order they were published,
+// until ctx is done or the notifications cannot be read any more
+type ConceptChangeConsumer interface {
+	Consume(ctx context.Context, handle func(ConceptChange)) error
+}
+
+// MemoryConsumer delivers the concept changes published to it in the same process, for tests
+type MemoryConsumer struct {
+	changes chan ConceptChange
+}
+
+// NewMemoryConsumer holds up to buffer changes that have been published but not consumed yet
+func NewMemoryConsumer(buffer int) *MemoryConsumer {
+	return &MemoryConsumer{changes: make(chan ConceptChange, buffer)}
+}
+
+// Publish queues the change for the consumer, waiting while the buffer is full
+func (c *MemoryConsumer) Publish(change ConceptChange) {
+	c.changes <- change
+}
+
+func (c *MemoryConsumer) Consume(ctx context.Context, handle func(ConceptChange)) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case change := <-c.changes:
+			handle(change)
+		}
+	}
+}
+
+// FileConsumer follows a local file of concept changes, one json notification per line, delivering the changes
+// already in it and then those appended to it. It stands in for the message bus in tests.
+type FileConsumer struct {
+	path         string
+	pollInterval time.Duration
+}
+
+// NewFileConsumer checks the file at path for new lines every pollInterval
+func NewFileConsumer(path string, pollInterval time.Duration) *FileConsumer {
+	return &FileConsumer{path: path, pollInterval: pollInterval}
+}
+
+func (c *FileConsumer) Consume(ctx context.Context, handle func(ConceptChange)) error {
+	f, err := os.Open(c.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	reader := bufio.NewReader(f)
+	var partial string
+	for {
+		line, err := reader.ReadString('\n')
+		if err == io.EOF {
+			// an incomplete line is waited on until the rest of it has been written
+			partial += line
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+				continue
+			}
+		}
+		if err != nil {
+			return err
+		}
+		line, partial = partial+line, ""
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var change ConceptChange
+		if err := json.Unmarshal([]byte(line), &change); err != nil || change.UUID == "" {
+			logger.WithError(err).Warnf("ignoring concept change that is not a json object with a uuid: %s", strings.TrimSpace(line))
+			continue
+		}
+		handle(change)
+	}
+}
@@ -0,0 +1,175 @@
+package brands
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	ftUUID        = "dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54"
+	lexUUID       = "2d3e16e0-61cb-4322-8aff-3b01c59f4daa"
+	lexAliasUUID  = "5c7592a8-1f0c-11e4-b0cb-b2227cce2b54"
+	lexLiveUUID   = "e363dfb8-f6d9-4f2c-beba-5162b334272b"
+	lexMarketUUID = "0be232ac-841f-11e8-8f42-da24cd01f044"
+	unrelatedUUID = "89d15f70-640d-11e4-9803-0800200c9a66"
+)
+
+// newFamilyCache caches FT, its child Lex, also cached by an alias, and Lex Live which is a child of Lex along
+// with Lex Market, which is not cached, and a brand unrelated to any of them
+func newFamilyCache(registry metrics.Registry) *CachingSource {
+	thing := func(UUID string) Thing { return Thing{ID: thingsApiUrl + UUID} }
+	lex := Brand{Thing: thing(lexUUID), Parent: &Thing{ID: thingsApiUrl + ftUUID}, Children: []Thing{thing(lexLiveUUID), thing(lexMarketUUID)}}
+
+	source := NewCachingSource(&fakeBrandSource{}, 10, time.Minute, 0, registry)
+	source.cache.add(ftUUID, cachedBrand{brand: Brand{Thing: thing(ftUUID), Children: []Thing{thing(lexUUID)}}, canonicalUUID: ftUUID})
+	source.cache.add(lexUUID, cachedBrand{brand: lex, canonicalUUID: lexUUID})
+	source.cache.add(lexAliasUUID, cachedBrand{brand: lex, canonicalUUID: lexUUID})
+	source.cache.add(lexLiveUUID, cachedBrand{brand: Brand{Thing: thing(lexLiveUUID), Parents: []Thing{thing(lexUUID)}}, canonicalUUID: lexLiveUUID})
+	source.cache.add(unrelatedUUID, cachedBrand{brand: Brand{Thing: thing(unrelatedUUID)}, canonicalUUID: unrelatedUUID})
+	return source
+}
+
+func cachedUUIDs(source *CachingSource) []string {
+	var UUIDs []string
+	for UUID := range source.cache.entries {
+		UUIDs = append(UUIDs, UUID)
+	}
+	sort.Strings(UUIDs)
+	return UUIDs
+}
+
+func TestCachingSourceEvict(t *testing.T) {
+	type testCase struct {
+		name              string
+		changed           string
+		expectedEvicted   int
+		expectedRemaining []string
+	}
+
+	testCases := []testCase{
+		{
+			"Evict - Brand is evicted with its aliases, parents and children",
+			lexUUID,
+			4,
+			[]string{unrelatedUUID},
+		},
+		{
+			"Evict - Brand is evicted with its parent under every uuid",
+			lexLiveUUID,
+			3,
+			[]string{unrelatedUUID, ftUUID},
+		},
+		{
+			"Evict - Brand that is not cached evicts the brands related to it",
+			lexMarketUUID,
+			2,
+			[]string{unrelatedUUID, ftUUID, lexLiveUUID},
+		},
+		{
+			"Evict - Alias evicts the brand it is an alias of",
+			lexAliasUUID,
+			4,
+			[]string{unrelatedUUID},
+		},
+		{
+			"Evict - Unknown brand evicts nothing",
+			"99999999-1f0c-11e4-b0cb-b2227cce2b54",
+			0,
+			[]string{unrelatedUUID, lexAliasUUID, lexUUID, ftUUID, lexLiveUUID},
+		},
+	}
+
+	for _, test := range testCases {
+		registry := metrics.NewRegistry()
+		source := newFamilyCache(registry)
+
+		evicted := source.Evict(test.changed)
+
+		sort.Strings(test.expectedRemaining)
+		assert.Equal(t, test.expectedEvicted, evicted, test.name+" failed: evicted brands do not match!")
+		assert.Equal(t, test.expectedRemaining, cachedUUIDs(source), test.name+" failed: cached brands do not match!")
+		assert.Equal(t, int64(test.expectedEvicted), metrics.GetOrRegisterCounter("brands.cache.evicted", registry).Count(), test.name+" failed: evicted count does not match!")
+	}
+}
+
+func TestCachingSourceDoesNotCacheReadDuringEviction(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	upstream := &blockingBrandSource{started: make(chan struct{}), release: make(chan struct{}), brand: Brand{Thing: Thing{PrefLabel: "Lex"}}, found: true}
+	source := NewCachingSource(upstream, 10, time.Minute, 0, metrics.NewRegistry())
+
+	read := make(chan struct{})
+	go func() {
+		source.GetBrand(context.Background(), lexUUID, "tid_test")
+		close(read)
+	}()
+	<-upstream.started
+	source.Evict(lexUUID)
+	close(upstream.release)
+	<-read
+
+	assert.Empty(t, cachedUUIDs(source), "brand read before the eviction should not be cached")
+	source.GetBrand(context.Background(), lexUUID, "tid_test")
+	assert.Equal(t, []string{lexUUID}, cachedUUIDs(source), "brand read after the eviction should be cached")
+	assert.Equal(t, 2, upstream.calls)
+}
+
+func TestConsumeChangesFromMemory(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	source := newFamilyCache(metrics.NewRegistry())
+	consumer := NewMemoryConsumer(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- source.ConsumeChanges(ctx, consumer) }()
+
+	consumer.Publish(ConceptChange{UUID: lexLiveUUID, TransactionID: "tid_test"})
+	consumer.Publish(ConceptChange{UUID: unrelatedUUID, TransactionID: "tid_test"})
+	consumer.Publish(ConceptChange{UUID: ftUUID, TransactionID: "tid_test"})
+	cancel()
+
+	assert.Equal(t, context.Canceled, <-done)
+	assert.Empty(t, cachedUUIDs(source))
+}
+
+func TestConsumeChangesFromFile(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	dir, err := ioutil.TempDir("", "concept-changes")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "changes.json")
+	assert.NoError(t, ioutil.WriteFile(path, []byte(`{"uuid": "`+lexLiveUUID+`"}`+"\nnot json\n\n"+`{"uuid": "`+unrelatedUUID[:8]), 0644))
+
+	consumer := NewFileConsumer(path, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	changes := make(chan ConceptChange, 2)
+	go consumer.Consume(ctx, func(change ConceptChange) { changes <- change })
+
+	assert.Equal(t, ConceptChange{UUID: lexLiveUUID}, <-changes)
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.NoError(t, err)
+	f.WriteString(unrelatedUUID[8:] + `"}` + "\n")
+	f.Close()
+
+	select {
+	case change := <-changes:
+		assert.Equal(t, ConceptChange{UUID: unrelatedUUID}, change)
+	case <-time.After(time.Second):
+		t.Error("change appended to the file was not consumed")
+	}
+}
+
+func TestFileConsumerFailsWithoutFile(t *testing.T) {
+	consumer := NewFileConsumer(filepath.Join(os.TempDir(), "does-not-exist", "changes.json"), time.Millisecond)
+	err := consumer.Consume(context.Background(), func(ConceptChange) {})
+	assert.True(t, os.IsNotExist(err))
+}
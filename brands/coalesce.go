@@ -0,0 +1,73 @@
+package brands
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	logger "github.com/Financial-Times/go-logger"
+)
+
+// CoalescingSource shares one read of a brand from another BrandSource between every concurrent request for
+// the same uuid, so that a popular brand expiring from downstream caches doesn't fan out into identical reads.
+// Errors and brands that were not found are shared in the same way.
+type CoalescingSource struct {
+	BrandSource
+	sync.Mutex
+	inFlight map[string]*brandCall
+}
+
+// brandCall is a read of a brand that other requests can wait on until done is closed
+type brandCall struct {
+	done          chan struct{}
+	transID       string
+	brand         Brand
+	canonicalUUID string
+	found         bool
+	err           error
+}
+
+func NewCoalescingSource(source BrandSource) *CoalescingSource {
+	return &CoalescingSource{
+		BrandSource: source,
+		inFlight:    map[string]*brandCall{},
+	}
+}
+
+func (s *CoalescingSource) GetBrand(ctx context.Context, UUID string, transID string) (Brand, string, bool, error) {
+	s.Lock()
+	if call, ok := s.inFlight[UUID]; ok {
+		s.Unlock()
+		logger.WithTransactionID(transID).WithUUID(UUID).Debugf("waiting for the read of brand started by %s", call.transID)
+		select {
+		case <-ctx.Done():
+			// the read carries on for the other requests waiting on it
+			return Brand{}, "", false, ctx.Err()
+		case <-call.done:
+		}
+		if call.err != nil && isContextError(call.err) && ctx.Err() == nil {
+			// the read was given up by the request that started it, not this one
+			return s.GetBrand(ctx, UUID, transID)
+		}
+		if call.err != nil {
+			logger.WithError(call.err).WithTransactionID(transID).WithUUID(UUID).Errorf("shared read of brand started by %s failed", call.transID)
+		}
+		return call.brand, call.canonicalUUID, call.found, call.err
+	}
+	call := &brandCall{done: make(chan struct{}), transID: transID}
+	s.inFlight[UUID] = call
+	s.Unlock()
+
+	defer func() {
+		s.Lock()
+		delete(s.inFlight, UUID)
+		s.Unlock()
+		close(call.done)
+	}()
+	call.brand, call.canonicalUUID, call.found, call.err = s.BrandSource.GetBrand(ctx, UUID, transID)
+	return call.brand, call.canonicalUUID, call.found, call.err
+}
+
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,175 @@
+package brands
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoalescingSource(t *testing.T) {
+	type testCase struct {
+		name          string
+		upstreamBrand Brand
+		upstreamFound bool
+		upstreamErr   error
+	}
+
+	testCases := []testCase{
+		{
+			"Coalescing Source - Concurrent requests share the brand",
+			Brand{Thing: Thing{PrefLabel: "Lex"}},
+			true,
+			nil,
+		},
+		{
+			"Coalescing Source - Concurrent requests share not found",
+			Brand{},
+			false,
+			nil,
+		},
+		{
+			"Coalescing Source - Concurrent requests share the error",
+			Brand{},
+			false,
+			errors.New("upstream failed"),
+		},
+	}
+
+	for _, test := range testCases {
+		upstream := &blockingBrandSource{
+			started: make(chan struct{}),
+			release: make(chan struct{}),
+			brand:   test.upstreamBrand,
+			found:   test.upstreamFound,
+			err:     test.upstreamErr,
+		}
+		source := NewCoalescingSource(upstream)
+
+		type result struct {
+			brand Brand
+			found bool
+			err   error
+		}
+		results := make([]result, 5)
+		waiters := newWaitingContext(context.Background(), len(results))
+		var wg sync.WaitGroup
+		get := func(ctx context.Context, i int) {
+			defer wg.Done()
+			brand, _, found, err := source.GetBrand(ctx, "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "tid_test")
+			results[i] = result{brand, found, err}
+		}
+
+		wg.Add(len(results))
+		go get(context.Background(), 0)
+		<-upstream.started
+		for i := 1; i < len(results); i++ {
+			go get(waiters, i)
+		}
+		waiters.waitFor(len(results) - 1)
+		close(upstream.release)
+		wg.Wait()
+
+		assert.Equal(t, 1, upstream.calls, test.name+" failed: upstream calls do not match!")
+		for _, r := range results {
+			assert.Equal(t, result{test.upstreamBrand, test.upstreamFound, test.upstreamErr}, r, test.name+" failed: results do not match!")
+		}
+	}
+}
+
+func TestCoalescingSourceWaiterGivesUp(t *testing.T) {
+	upstream := &blockingBrandSource{started: make(chan struct{}), release: make(chan struct{})}
+	source := NewCoalescingSource(upstream)
+	defer close(upstream.release)
+
+	go source.GetBrand(context.Background(), "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "tid_leader")
+	<-upstream.started
+	ctx, cancel := context.WithCancel(context.Background())
+	waiter := newWaitingContext(ctx, 1)
+	waited := make(chan error)
+	go func() {
+		_, _, _, err := source.GetBrand(waiter, "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "tid_waiter")
+		waited <- err
+	}()
+	waiter.waitFor(1)
+	cancel()
+
+	select {
+	case err := <-waited:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(time.Second):
+		t.Error("waiter whose request was cancelled kept waiting for the read")
+	}
+	assert.Equal(t, 1, upstream.calls)
+}
+
+func TestCoalescingSourceReadsAgainOnceDone(t *testing.T) {
+	upstream := &fakeBrandSource{}
+	source := NewCoalescingSource(upstream)
+
+	source.GetBrand(context.Background(), "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "tid_first")
+	source.GetBrand(context.Background(), "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "tid_second")
+
+	assert.Equal(t, 2, upstream.calls)
+}
+
+// waitingContext counts the requests that have started waiting on it, which a request waiting on the read of
+// another does by selecting on Done. The reads upstream are given other contexts, so they are not counted.
+type waitingContext struct {
+	context.Context
+	waiting chan struct{}
+}
+
+func newWaitingContext(ctx context.Context, capacity int) *waitingContext {
+	return &waitingContext{Context: ctx, waiting: make(chan struct{}, capacity)}
+}
+
+func (c *waitingContext) Done() <-chan struct{} {
+	c.waiting <- struct{}{}
+	return c.Context.Done()
+}
+
+// waitFor returns once n requests are waiting on the context
+func (c *waitingContext) waitFor(n int) {
+	for i := 0; i < n; i++ {
+		<-c.waiting
+	}
+}
+
+// blockingBrandSource signals started on the first read and holds every read until release is closed
+type blockingBrandSource struct {
+	sync.Mutex
+	calls   int
+	started chan struct{}
+	release chan struct{}
+	brand   Brand
+	found   bool
+	err     error
+}
+
+func (b *blockingBrandSource) GetBrand(ctx context.Context, UUID string, transID string) (Brand, string, bool, error) {
+	b.Lock()
+	b.calls++
+	if b.calls == 1 {
+		close(b.started)
+	}
+	b.Unlock()
+	<-b.release
+	return b.brand, UUID, b.found, b.err
+}
+
+func (b *blockingBrandSource) GetBrands(ctx context.Context, transID string) ([]Brand, error) {
+	return nil, nil
+}
+
+func (b *blockingBrandSource) GetBrandUUIDByIdentifier(ctx context.Context, authority string, identifierValue string, transID string) (string, bool, error) {
+	return "", false, nil
+}
+
+func (b *blockingBrandSource) HealthCheck() fthealth.Check {
+	return fthealth.Check{}
+}
@@ -0,0 +1,314 @@
+package brands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
+	logger "github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/neo-model-utils-go/mapper"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const queryParams = "?showRelationship=broader&showRelationship=narrower"
+
+type httpClient interface {
+	Do(req *http.Request) (resp *http.Response, err error)
+}
+
+// unwrapper is implemented by http clients that wrap another one, such as the retrying and circuit breaker clients
+type unwrapper interface {
+	Unwrap() httpClient
+}
+
+// unwrapClient returns the client at the bottom of a chain of wrapping clients. Health checks use it so that they
+// report the health of the upstream itself, and so that their outcome does not change the state of the wrappers.
+func unwrapClient(client httpClient) httpClient {
+	for {
+		wrapper, ok := client.(unwrapper)
+		if !ok {
+			return client
+		}
+		client = wrapper.Unwrap()
+	}
+}
+
+// ConceptsAPISource reads brands from public-concepts-api, and resolves alternative identifiers with public-concordances-api
+type ConceptsAPISource struct {
+	client          httpClient
+	conceptsURL     string
+	concordancesURL string
+}
+
+func NewConceptsAPISource(client httpClient, conceptsURL string, concordancesURL string) *ConceptsAPISource {
+	return &ConceptsAPISource{
+		client:          client,
+		conceptsURL:     conceptsURL,
+		concordancesURL: concordancesURL,
+	}
+}
+
+func (s *ConceptsAPISource) Checker() (string, error) {
+	req, err := http.NewRequest("GET", s.conceptsURL+"/__gtg", nil)
+	if err != nil {
+		return "", err
+	}
+
+	req.Header.Add("User-Agent", "UPP public-brands-api")
+
+	resp, err := unwrapClient(s.client).Do(req)
+	if err != nil {
+		return "", err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("health check returned a non-200 HTTP status: %v", resp.StatusCode)
+	}
+	return "Public Concepts API is healthy", nil
+}
+
+func (s *ConceptsAPISource) HealthCheck() fthealth.Check {
+	return fthealth.Check{
+		ID:               "public-concepts-api-check",
+		BusinessImpact:   "Unable to respond to Public Brands api requests",
+		Name:             "Check connectivity to public-concepts-api",
+		PanicGuide:       "https://runbooks.in.ft.com/public-brands-api",
+		Severity:         2,
+		TechnicalSummary: "Not being able to communicate with public-concepts-api means that requests for organisations cannot be performed.",
+		Checker:          s.Checker,
+	}
+}
+
+func (s *ConceptsAPISource) GetBrand(ctx context.Context, UUID string, transID string) (Brand, string, bool, error) {
+	return s.getBrandViaConceptsAPI(ctx, UUID, transID)
+}
+
+func (s *ConceptsAPISource) GetBrands(ctx context.Context, transID string) ([]Brand, error) {
+	concepts, err := s.getBrandConceptsViaConceptsAPI(ctx, transID)
+	if err != nil {
+		return nil, err
+	}
+
+	var brands []Brand
+	for _, concept := range concepts {
+		brands = append(brands, Brand{
+			Thing:   convertConcept(concept),
+			Aliases: convertAliases(ConceptApiResponse{Concept: concept}),
+		})
+	}
+	return brands, nil
+}
+
+// GetBrandUUIDByIdentifier looks the identifier up in the concordances api, then resolves the concept it belongs to
+// via the concepts api so that only canonical brands are returned.
+func (s *ConceptsAPISource) GetBrandUUIDByIdentifier(ctx context.Context, authority string, identifierValue string, transID string) (canonicalUUID string, found bool, err error) {
+	logger.WithTransactionID(transID).Debug("retrieving brand by identifier via concordances api")
+	reqURL := s.concordancesURL + "/concordances?authority=" + url.QueryEscape(identifierAuthorities[authority]) + "&identifierValue=" + url.QueryEscape(identifierValue)
+
+	concordances := ConcordancesApiResponse{}
+	found, _, err = s.getJSON(ctx, reqURL, "", transID, &concordances)
+	if err != nil || !found || len(concordances.Concordances) == 0 {
+		return "", false, err
+	}
+
+	UUID := uuidFromID(concordances.Concordances[0].Concept.ID)
+	_, canonicalUUID, found, err = s.getBrandViaConceptsAPI(ctx, UUID, transID)
+	return canonicalUUID, found, err
+}
+
+func (s *ConceptsAPISource) getBrandViaConceptsAPI(ctx context.Context, UUID string, transID string) (brand Brand, canonicalUuid string, found bool, err error) {
+	logger.WithTransactionID(transID).WithUUID(UUID).Debug("retrieving brand via concepts api")
+	mappedBrand := Brand{}
+	reqURL := s.conceptsURL + "/concepts/" + UUID + queryParams
+
+	start := time.Now()
+	conceptsApiResponse := ConceptApiResponse{}
+	found, header, err := s.getJSON(ctx, reqURL, UUID, transID, &conceptsApiResponse)
+	if err != nil {
+		observeConceptsAPICall(start, outcomeError)
+		return mappedBrand, "", false, err
+	}
+	if !found {
+		observeConceptsAPICall(start, outcomeNotFound)
+		return mappedBrand, "", false, nil
+	}
+
+	if conceptsApiResponse.Type != brandOntology {
+		observeConceptsAPICall(start, outcomeNonBrand)
+		logger.WithTransactionID(transID).WithUUID(UUID).Debug("requested concept is not a brand")
+		mappedBrand.Thing = Thing{ID: convertID(conceptsApiResponse.ID), DirectType: conceptsApiResponse.Type}
+		return mappedBrand, "", false, nil
+	}
+
+	_, mapSpan := startSpan(ctx, "mapBrand", trace.SpanKindInternal, attribute.String("brand.uuid", UUID))
+	mappedBrand = mapBrand(conceptsApiResponse)
+	mapSpan.End()
+	if lastModified, err := http.ParseTime(header.Get("Last-Modified")); err == nil {
+		mappedBrand.LastModified = lastModified
+	}
+	canonicalUuid = uuidFromID(mappedBrand.ID)
+	if canonicalUuid != UUID {
+		observeConceptsAPICall(start, outcomeRedirect)
+	} else {
+		observeConceptsAPICall(start, outcomeFound)
+	}
+	return mappedBrand, canonicalUuid, true, nil
+}
+
+// getJSON requests reqURL from an upstream api, unmarshals the response body into v and returns the response headers.
+// A 404 from the upstream api is reported as not found rather than as an error, any other status but 200 is an error.
+func (s *ConceptsAPISource) getJSON(ctx context.Context, reqURL string, UUID string, transID string, v interface{}) (found bool, header http.Header, err error) {
+	ctx, span := startSpan(ctx, "HTTP GET", trace.SpanKindClient, attribute.String("http.url", reqURL))
+	defer func() {
+		recordSpanError(span, err)
+		span.End()
+	}()
+
+	request, err := http.NewRequestWithContext(ctx, "GET", reqURL, nil)
+	if err != nil {
+		msg := fmt.Sprintf("failed to create request to %s", reqURL)
+		logger.WithError(err).WithUUID(UUID).WithTransactionID(transID).Error(msg)
+		return false, nil, err
+	}
+
+	request.Header.Set("X-Request-Id", transID)
+	propagator.Inject(ctx, propagation.HeaderCarrier(request.Header))
+	resp, err := s.client.Do(request)
+	if err != nil {
+		msg := fmt.Sprintf("request to %s failed", reqURL)
+		logger.WithError(err).WithUUID(UUID).WithTransactionID(transID).Error(msg)
+		return false, nil, err
+	}
+	defer resp.Body.Close()
+	span.SetAttributes(attribute.Int("http.status_code", resp.StatusCode))
+	if resp.StatusCode == http.StatusNotFound {
+		return false, resp.Header, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("%s returned status %d", reqURL, resp.StatusCode)
+		logger.WithError(err).WithUUID(UUID).WithTransactionID(transID).Error("request to upstream api failed")
+		return false, nil, err
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		msg := fmt.Sprintf("failed to read response body: %v", resp.Body)
+		logger.WithError(err).WithUUID(UUID).WithTransactionID(transID).Error(msg)
+		return false, nil, err
+	}
+	if err = json.Unmarshal(body, v); err != nil {
+		msg := fmt.Sprintf("failed to unmarshal response body: %v", body)
+		logger.WithError(err).WithUUID(UUID).WithTransactionID(transID).Error(msg)
+		return false, nil, err
+	}
+	return true, resp.Header, nil
+}
+
+func mapBrand(conceptsApiResponse ConceptApiResponse) Brand {
+	mappedBrand := Brand{}
+	mappedBrand.ID = convertID(conceptsApiResponse.ID)
+	mappedBrand.APIURL = convertApiUrl(conceptsApiResponse.ApiURL)
+	mappedBrand.PrefLabel = conceptsApiResponse.PrefLabel
+	mappedBrand.IsDeprecated = conceptsApiResponse.IsDeprecated
+	mappedBrand.Types = mapper.FullTypeHierarchy(conceptsApiResponse.Type)
+	mappedBrand.DirectType = conceptsApiResponse.Type
+	mappedBrand.ImageURL = conceptsApiResponse.ImageURL
+	mappedBrand.DescriptionXML = conceptsApiResponse.DescriptionXML
+	mappedBrand.Strapline = conceptsApiResponse.Strapline
+	mappedBrand.Aliases = convertAliases(conceptsApiResponse)
+	mappedBrand.AlternativeIdentifiers = conceptsApiResponse.AlternativeIdentifiers
+
+	for _, broader := range conceptsApiResponse.Broader {
+		if broader.Concept.Type == brandOntology {
+			if mappedBrand.Parent == nil {
+				mappedBrand.Parent = convertRelationship(broader)
+			}
+			mappedBrand.Parents = append(mappedBrand.Parents, *convertRelationship(broader))
+		}
+	}
+	var children []Thing
+	for _, narrower := range conceptsApiResponse.Narrower {
+		children = append(children, *convertRelationship(narrower))
+	}
+	mappedBrand.Children = children
+	return mappedBrand
+}
+
+func convertRelationship(rc RelatedConcept) *Thing {
+	return &Thing{
+		ID:         convertID(rc.Concept.ID),
+		APIURL:     convertApiUrl(rc.Concept.ApiURL),
+		Types:      mapper.FullTypeHierarchy(rc.Concept.Type),
+		DirectType: rc.Concept.Type,
+		PrefLabel:  rc.Concept.PrefLabel,
+	}
+}
+
+// convertAliases combines the alternative labels of the concept with any aliases it has, without duplicates
+func convertAliases(conceptsApiResponse ConceptApiResponse) []string {
+	var aliases []string
+	seen := map[string]bool{}
+	for _, label := range conceptsApiResponse.AlternativeLabels {
+		if !seen[label.Value] {
+			seen[label.Value] = true
+			aliases = append(aliases, label.Value)
+		}
+	}
+	for _, alias := range conceptsApiResponse.Aliases {
+		if !seen[alias] {
+			seen[alias] = true
+			aliases = append(aliases, alias)
+		}
+	}
+	return aliases
+}
+
+func convertConcept(c Concept) Thing {
+	return Thing{
+		ID:           convertID(c.ID),
+		APIURL:       convertApiUrl(c.ApiURL),
+		Types:        mapper.FullTypeHierarchy(c.Type),
+		DirectType:   c.Type,
+		PrefLabel:    c.PrefLabel,
+		IsDeprecated: c.IsDeprecated,
+	}
+}
+
+func convertApiUrl(conceptsApiUrl string) string {
+	return strings.Replace(conceptsApiUrl, "concepts", "brands", 1)
+}
+
+func convertID(conceptsApiID string) string {
+	return strings.Replace(conceptsApiID, ftThing, thingsApiUrl, 1)
+}
+
+// getBrandConceptsViaConceptsAPI returns every brand known to the concepts api, including deprecated ones
+func (s *ConceptsAPISource) getBrandConceptsViaConceptsAPI(ctx context.Context, transID string) ([]Concept, error) {
+	logger.WithTransactionID(transID).Debug("retrieving brands via concepts api")
+	reqURL := s.conceptsURL + "/concepts?type=" + url.QueryEscape(brandOntology) + "&includeDeprecated=true"
+
+	searchResponse := ConceptSearchApiResponse{}
+	found, _, err := s.getJSON(ctx, reqURL, "", transID, &searchResponse)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	var concepts []Concept
+	for _, concept := range searchResponse.Concepts {
+		if concept.Type != brandOntology {
+			continue
+		}
+		concepts = append(concepts, concept)
+	}
+	return concepts, nil
+}
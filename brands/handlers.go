@@ -1,82 +1,122 @@
 package brands
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"net/http"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"fmt"
-	"io/ioutil"
 
 	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
 	logger "github.com/Financial-Times/go-logger"
-	"github.com/Financial-Times/neo-model-utils-go/mapper"
 	"github.com/Financial-Times/service-status-go/gtg"
 	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
 	"github.com/gorilla/handlers"
 	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // CacheControlHeader is the value to set on http header
 var CacheControlHeader string
 
-type httpClient interface {
-	Do(req *http.Request) (resp *http.Response, err error)
-}
+// MaxTreeDepth is the deepest brand tree that can be requested from /brands/{uuid}/tree
+var MaxTreeDepth = 5
+
+// TreeConcurrency is how many brands of a level of a brand tree are requested from the brand source at the same time
+var TreeConcurrency = 8
+
+// MaxBatchSize is the most uuids that can be requested at once from /brands/__batch
+var MaxBatchSize = 500
+
+// BatchConcurrency is how many brands of a batch are requested from the brand source at the same time
+var BatchConcurrency = 8
+
+// RequestTimeout is how long a request can spend reading from the brand source before it gets a 504, 0 is no limit
+var RequestTimeout time.Duration
+
+// SearchRefreshTimeout is how long reloading the search index from the brand source can take before it is given up
+var SearchRefreshTimeout = time.Minute
+
+var uuidMatcher = regexp.MustCompile(validUUID)
+
+const (
+	staleWarning = `110 - "Response is Stale"`
+	// staleCacheControlHeader lets downstream caches hold on to a stale brand only briefly, so that the
+	// fresh brand is picked up soon after the upstream recovers
+	staleCacheControlHeader = "max-age=60, public"
+)
 
 const (
 	validUUID     = "([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})$"
 	thingsApiUrl  = "http://api.ft.com/things/"
+	brandsApiUrl  = "http://api.ft.com/brands/"
 	ftThing       = "http://www.ft.com/thing/"
 	brandOntology = "http://www.ft.com/ontology/product/Brand"
-	queryParams   = "?showRelationship=broader&showRelationship=narrower"
 )
 
-type BrandsHandler struct {
-	client      httpClient
-	conceptsURL string
+// publicAPIPaths are the paths of the public apis that serve concepts of other types than brands, by direct type.
+// Concepts of any other type are served by the things api.
+var publicAPIPaths = map[string]string{
+	"http://www.ft.com/ontology/person/Person":             "/people/",
+	"http://www.ft.com/ontology/organisation/Organisation": "/organisations/",
+	"http://www.ft.com/ontology/company/Company":           "/organisations/",
+	"http://www.ft.com/ontology/company/PublicCompany":     "/organisations/",
+	"http://www.ft.com/ontology/company/PrivateCompany":    "/organisations/",
 }
 
-func NewHandler(client httpClient, conceptsURL string) BrandsHandler {
-	return BrandsHandler{
-		client:      client,
-		conceptsURL: conceptsURL,
+// publicAPIPath is the path a concept of directType is served at by the public apis
+func publicAPIPath(directType string, UUID string) string {
+	if path, ok := publicAPIPaths[directType]; ok {
+		return path + UUID
 	}
+	return "/things/" + UUID
 }
 
-func (h *BrandsHandler) Checker() (string, error) {
-	req, err := http.NewRequest("GET", h.conceptsURL+"/__gtg", nil)
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Add("User-Agent", "UPP public-brands-api")
+type BrandsHandler struct {
+	source      BrandSource
+	searchIndex *searchIndex
+	brandList   *brandList
+	// checks are reported along with the health check of the source, such as the state of its circuit breaker
+	checks []fthealth.Check
+	// draining is set to 1 once the service starts shutting down
+	draining *int32
+}
 
-	resp, err := h.client.Do(req)
-	if err != nil {
-		return "", err
+// NewHandler serves brands from source. Its health checks are the check of the source followed by checks.
+func NewHandler(source BrandSource, checks ...fthealth.Check) BrandsHandler {
+	return BrandsHandler{
+		source:      source,
+		checks:      checks,
+		searchIndex: &searchIndex{},
+		brandList:   &brandList{},
+		draining:    new(int32),
 	}
+}
 
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("health check returned a non-200 HTTP status: %v", resp.StatusCode)
-	}
-	return "Public Concepts API is healthy", nil
+// Drain makes the service not good to go while it shuts down, so that no new requests are routed to it
+// while the ones in flight finish
+func (h *BrandsHandler) Drain() {
+	atomic.StoreInt32(h.draining, 1)
+}
 
+func (h *BrandsHandler) Checker() (string, error) {
+	return h.source.HealthCheck().Checker()
 }
 
 func (h *BrandsHandler) HealthCheck() fthealth.Check {
-	return fthealth.Check{
-		ID:               "public-concepts-api-check",
-		BusinessImpact:   "Unable to respond to Public Brands api requests",
-		Name:             "Check connectivity to public-concepts-api",
-		PanicGuide:       "https://runbooks.in.ft.com/public-brands-api",
-		Severity:         2,
-		TechnicalSummary: "Not being able to communicate with public-concepts-api means that requests for organisations cannot be performed.",
-		Checker:          h.Checker,
-	}
+	return h.source.HealthCheck()
+}
+
+// HealthChecks are the checks reported by /__health, which /__gtg runs as well
+func (h *BrandsHandler) HealthChecks() []fthealth.Check {
+	return append([]fthealth.Check{h.HealthCheck()}, h.checks...)
 }
 
 // MethodNotAllowedHandler does stuff
@@ -88,157 +128,206 @@ func (h *BrandsHandler) MethodNotAllowedHandler(w http.ResponseWriter, r *http.R
 func (h *BrandsHandler) RegisterHandlers(router *mux.Router) {
 	logger.Info("Registering handlers")
 	mh := handlers.MethodHandler{
-		"GET": http.HandlerFunc(h.GetBrand),
+		"GET": withRequestTimeout(withContentNegotiation(brandEncoders, h.GetBrand)),
+	}
+
+	listMh := handlers.MethodHandler{
+		"GET": withRequestTimeout(withContentNegotiation(listEncoders, h.ListBrands)),
+	}
+	treeMh := handlers.MethodHandler{
+		"GET": withRequestTimeout(h.GetBrandTree),
+	}
+	ancestorsMh := handlers.MethodHandler{
+		"GET": withRequestTimeout(h.GetBrandAncestors),
+	}
+	batchMh := handlers.MethodHandler{
+		"POST": withRequestTimeout(h.GetBrandBatch),
+	}
+	searchMh := handlers.MethodHandler{
+		"GET": http.HandlerFunc(h.SearchBrands),
 	}
 
 	// These paths need to actually be the concept type
-	router.Handle("/brands/{uuid}", mh)
+	router.Handle("/brands", withRequestMetrics("/brands", listMh))
+	router.Handle("/brands/__batch", withRequestMetrics("/brands/__batch", batchMh))
+	router.Handle("/brands/search", withRequestMetrics("/brands/search", searchMh))
+	router.Handle("/brands/{uuid}", withRequestMetrics("/brands/{uuid}", mh))
+	router.Handle("/brands/{uuid}/tree", withRequestMetrics("/brands/{uuid}/tree", treeMh))
+	router.Handle("/brands/{uuid}/ancestors", withRequestMetrics("/brands/{uuid}/ancestors", ancestorsMh))
+}
+
+// withRequestTimeout gives the request a deadline of RequestTimeout, which is passed on to the brand source
+// along with the cancellation of the request when the client goes away
+func withRequestTimeout(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if RequestTimeout <= 0 {
+			next(w, r)
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), RequestTimeout)
+		defer cancel()
+		next(w, r.WithContext(ctx))
+	}
 }
 
 // GetBrand is the public API
 func (h *BrandsHandler) GetBrand(w http.ResponseWriter, r *http.Request) {
-	uuidMatcher := regexp.MustCompile(validUUID)
 	vars := mux.Vars(r)
 	UUID := vars["uuid"]
 	transID := transactionidutils.GetTransactionIDFromRequest(r)
-	w.Header().Set("Content-Type", "application/json")
+	ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	ctx, span := startSpan(ctx, "GetBrand", trace.SpanKindServer, attribute.String("brand.uuid", UUID))
+	defer span.End()
+	r = r.WithContext(ctx)
 	w.Header().Set("Cache-Control", CacheControlHeader)
 
-	if UUID == "" || !uuidMatcher.MatchString(UUID) {
-		msg := fmt.Sprintf(`uuid '%s' is either missing or invalid`, UUID)
-		logger.WithTransactionID(transID).WithUUID(UUID).Error(msg)
-		w.WriteHeader(http.StatusBadRequest)
-		w.Write([]byte(`{"message": "` + msg + `"}`))
-		return
-	}
-
-	brand, canonicalUUID, found, err := h.getBrandViaConceptsAPI(UUID, transID)
+	version, err := requestedVersion(r)
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"message": "failed to return brand"}`))
+		writeProblem(w, r, problemInvalidRequest, err.Error())
 		return
 	}
 
-	if found && canonicalUUID != "" && canonicalUUID != UUID {
-		redirectURL := strings.Replace(r.RequestURI, UUID, canonicalUUID, 1)
-		logger.WithTransactionID(transID).WithUUID(UUID).Debug("serving redirect")
-		w.Header().Set("Location", redirectURL)
-		w.WriteHeader(http.StatusMovedPermanently)
+	brand, ok := h.resolveBrand(w, r, UUID, transID)
+	if !ok {
 		return
 	}
-	if !found {
-		msg := fmt.Sprint("brand not found")
-		w.WriteHeader(http.StatusNotFound)
-		logger.WithTransactionID(transID).WithUUID(UUID).Info(msg)
-		w.Write([]byte(`{"message": "` + msg + `"}`))
-		return
+	if brand.Stale {
+		w.Header().Set("Warning", staleWarning)
+		w.Header().Set("Cache-Control", staleCacheControlHeader)
 	}
 
 	logger.Debugf("Brand (uuid): %s\n", brand.ID)
 
-	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(brand)
+	query := r.URL.Query()
+	brand = brand.withOptionalFields(query.Get("showAliases") == "true", query.Get("showAlternativeIdentifiers") == "true")
+	if encoderFor(r).Versioned {
+		brand = brand.forVersion(version)
+	}
+	body, contentType, err := encode(r, brand)
 	if err != nil {
 		msg := fmt.Sprintf("brand: %v could not be marshaled", brand)
 		logger.WithError(err).WithTransactionID(transID).WithUUID(UUID).Error(msg)
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(`{"message": "` + msg + `"}`))
+		writeProblem(w, r, problemInternal, msg)
+		return
+	}
+	if contentType == "application/json" && version != defaultVersion {
+		contentType = fmt.Sprintf("application/json; version=%d", version)
 	}
-}
 
-//GoodToGo returns a 503 if the healthcheck fails - suitable for use from varnish to check availability of a node
-func (h *BrandsHandler) GTG() gtg.Status {
-	statusCheck := func() gtg.Status {
-		return gtgCheck(h.Checker)
+	etag := etagFor(body)
+	w.Header().Set("ETag", etag)
+	if !brand.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", brand.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if notModified(r, etag, brand.LastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
 	}
-	return gtg.FailFastParallelCheck([]gtg.StatusChecker{statusCheck})()
+
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
 }
 
-func gtgCheck(handler func() (string, error)) gtg.Status {
-	if _, err := handler(); err != nil {
-		return gtg.Status{GoodToGo: false, Message: err.Error()}
+// resolveBrand validates the requested uuid and retrieves its brand. When there is no brand to serve
+// the error, redirect or not found response is written and false is returned. The result is recorded
+// on the span of the request, if it has one.
+//
+// A concept that is not a brand is not found either, but with showDirectType=true the response says what
+// type it does have and links to the public api that serves it.
+func (h *BrandsHandler) resolveBrand(w http.ResponseWriter, r *http.Request, UUID string, transID string) (Brand, bool) {
+	span := trace.SpanFromContext(r.Context())
+	if UUID == "" || !uuidMatcher.MatchString(UUID) {
+		writeProblem(w, r, problemInvalidUUID, fmt.Sprintf(`uuid '%s' is either missing or invalid`, UUID))
+		return Brand{}, false
 	}
-	return gtg.Status{GoodToGo: true}
-}
 
-func (h *BrandsHandler) getBrandViaConceptsAPI(UUID string, transID string) (brand Brand, canonicalUuid string, found bool, err error) {
-	logger.WithTransactionID(transID).WithUUID(UUID).Debug("retrieving brand via concepts api")
-	mappedBrand := Brand{}
-	reqURL := h.conceptsURL + "/concepts/" + UUID + queryParams
-	request, err := http.NewRequest("GET", reqURL, nil)
+	brand, canonicalUUID, found, err := h.source.GetBrand(r.Context(), UUID, transID)
 	if err != nil {
-		msg := fmt.Sprintf("failed to create request to %s", reqURL)
-		logger.WithError(err).WithUUID(UUID).WithTransactionID(transID).Error(msg)
-		return mappedBrand, "", false, err
+		recordSpanError(span, err)
+		span.SetAttributes(attribute.String("brand.result", outcomeError))
+		writeSourceError(w, r, err, "failed to return brand")
+		return Brand{}, false
 	}
+	span.SetAttributes(attribute.String("brand.canonical_uuid", canonicalUUID))
 
-	request.Header.Set("X-Request-Id", transID)
-	resp, err := h.client.Do(request)
-	if err != nil {
-		msg := fmt.Sprintf("request to %s returned status: %d", reqURL, resp.StatusCode)
-		logger.WithError(err).WithUUID(UUID).WithTransactionID(transID).Error(msg)
-		return mappedBrand, "", false, err
+	if found && canonicalUUID != "" && canonicalUUID != UUID {
+		span.SetAttributes(attribute.String("brand.result", outcomeRedirect))
+		redirectURL := strings.Replace(r.RequestURI, UUID, canonicalUUID, 1)
+		logger.WithTransactionID(transID).WithUUID(UUID).Debug("serving redirect")
+		w.Header().Set("Location", redirectURL)
+		w.WriteHeader(http.StatusMovedPermanently)
+		return Brand{}, false
 	}
-	if resp.StatusCode == http.StatusNotFound {
-		return mappedBrand, "", false, nil
+	if !found && brand.DirectType != "" {
+		span.SetAttributes(attribute.String("brand.result", outcomeNonBrand))
+		p := newProblem(r, problemNonBrandConcept, "concept is not a brand")
+		if r.URL.Query().Get("showDirectType") == "true" {
+			p.Detail = fmt.Sprintf("concept is a %s rather than a brand", brand.DirectType)
+			p.DirectType = brand.DirectType
+			w.Header().Set("Link", fmt.Sprintf(`<%s>; rel="alternate"`, publicAPIPath(brand.DirectType, UUID)))
+		}
+		writeProblemDetails(w, r, p)
+		return Brand{}, false
 	}
+	if !found {
+		span.SetAttributes(attribute.String("brand.result", outcomeNotFound))
+		writeProblem(w, r, problemNotFound, "brand not found")
+		return Brand{}, false
+	}
+	span.SetAttributes(attribute.String("brand.result", outcomeFound))
+	return brand, true
+}
 
-	conceptsApiResponse := ConceptApiResponse{}
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		msg := fmt.Sprintf("failed to read response body: %v", resp.Body)
-		logger.WithError(err).WithUUID(UUID).WithTransactionID(transID).Error(msg)
-		return mappedBrand, "", false, err
-	}
-	if err = json.Unmarshal(body, &conceptsApiResponse); err != nil {
-		msg := fmt.Sprintf("failed to unmarshal response body: %v", body)
-		logger.WithError(err).WithUUID(UUID).WithTransactionID(transID).Error(msg)
-		return mappedBrand, "", false, err
-	}
-
-	if conceptsApiResponse.Type != brandOntology {
-		logger.WithTransactionID(transID).WithUUID(UUID).Debug("requested concept is not a brand")
-		return mappedBrand, "", false, nil
-	}
-
-	mappedBrand.ID = convertID(conceptsApiResponse.ID)
-	mappedBrand.APIURL = convertApiUrl(conceptsApiResponse.ApiURL)
-	mappedBrand.PrefLabel = conceptsApiResponse.PrefLabel
-	mappedBrand.IsDeprecated = conceptsApiResponse.IsDeprecated
-	mappedBrand.Types = mapper.FullTypeHierarchy(conceptsApiResponse.Type)
-	mappedBrand.DirectType = conceptsApiResponse.Type
-	mappedBrand.ImageURL = conceptsApiResponse.ImageURL
-	mappedBrand.DescriptionXML = conceptsApiResponse.DescriptionXML
-	mappedBrand.Strapline = conceptsApiResponse.Strapline
-
-	for _, broader := range conceptsApiResponse.Broader {
-		if broader.Concept.Type == brandOntology {
-			mappedBrand.Parent = convertRelationship(broader)
-			break
-		}
+// writeSourceError responds to a failure to read from the brand source. Running out of time is a 504 and while
+// the circuit breaker to the upstream is open the request fails fast with a 503 saying when to retry,
+// anything else is a 500 with msg.
+func writeSourceError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		writeProblem(w, r, problemUpstreamTimeout, "upstream did not respond in time")
+		return
 	}
-	var children []Thing
-	for _, narrower := range conceptsApiResponse.Narrower {
-		children = append(children, *convertRelationship(narrower))
+	var openErr *CircuitOpenError
+	if errors.As(err, &openErr) {
+		w.Header().Set("Retry-After", strconv.Itoa(openErr.retryAfterSeconds()))
+		writeProblem(w, r, problemUpstreamUnavailable, "upstream is unavailable, retry later")
+		return
 	}
-	mappedBrand.Children = children
-	return mappedBrand, strings.TrimPrefix(mappedBrand.ID, thingsApiUrl), true, nil
+	writeProblem(w, r, problemUpstreamFailure, msg)
 }
 
-func convertRelationship(rc RelatedConcept) *Thing {
-	return &Thing{
-		ID:         convertID(rc.Concept.ID),
-		APIURL:     convertApiUrl(rc.Concept.ApiURL),
-		Types:      mapper.FullTypeHierarchy(rc.Concept.Type),
-		DirectType: rc.Concept.Type,
-		PrefLabel:  rc.Concept.PrefLabel,
+// GTG fails when the brand source is unhealthy, unless stale brands are being served in the meantime
+// in which case the service is degraded but still good to go. It always fails once the service is draining.
+func (h *BrandsHandler) GTG() gtg.Status {
+	if atomic.LoadInt32(h.draining) == 1 {
+		return gtg.Status{GoodToGo: false, Message: "draining connections before shutting down"}
+	}
+	var statusChecks []gtg.StatusChecker
+	for _, check := range h.HealthChecks() {
+		checker := check.Checker
+		statusChecks = append(statusChecks, func() gtg.Status {
+			return gtgCheck(checker)
+		})
 	}
+	status := gtg.FailFastParallelCheck(statusChecks)()
+	if stale, ok := h.source.(staleServer); !status.GoodToGo && ok && stale.ServesStale() {
+		return gtg.Status{GoodToGo: true, Message: "degraded but serving stale brands: " + status.Message}
+	}
+	return status
+}
+
+// staleServer is implemented by brand sources that can keep serving brands while their upstream is failing
+type staleServer interface {
+	ServesStale() bool
 }
 
-func convertApiUrl(conceptsApiUrl string) string {
-	return strings.Replace(conceptsApiUrl, "concepts", "brands", 1)
+func gtgCheck(handler func() (string, error)) gtg.Status {
+	if _, err := handler(); err != nil {
+		return gtg.Status{GoodToGo: false, Message: err.Error()}
+	}
+	return gtg.Status{GoodToGo: true}
 }
 
-func convertID(conceptsApiID string) string {
-	return strings.Replace(conceptsApiID, ftThing, thingsApiUrl, 1)
+func uuidFromID(id string) string {
+	return strings.TrimPrefix(id, thingsApiUrl)
 }
@@ -2,15 +2,19 @@ package brands
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
 	"github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/service-status-go/gtg"
 	"github.com/gorilla/mux"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestHandlers(t *testing.T) {
@@ -34,7 +38,7 @@ func TestHandlers(t *testing.T) {
 		getBasicBrandAsConcept,
 		nil,
 		400,
-		`{"message": "uuid '1234' is either missing or invalid"}`,
+		problemJSON(problemInvalidUUID, "uuid '1234' is either missing or invalid", "/brands/1234"),
 	}
 	conceptApiError := testCase{
 		"Get Brand - Concepts API Error results in error",
@@ -43,7 +47,7 @@ func TestHandlers(t *testing.T) {
 		"",
 		errors.New("Downstream error"),
 		500,
-		`{"message": "failed to return brand"}`,
+		problemJSON(problemUpstreamFailure, "failed to return brand", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa"),
 	}
 	redirectedUUID := testCase{
 		"Get Brand - Given UUID was not canonical",
@@ -61,7 +65,7 @@ func TestHandlers(t *testing.T) {
 		`{`,
 		nil,
 		500,
-		`{"message": "failed to return brand"}`,
+		problemJSON(problemUpstreamFailure, "failed to return brand", "/brands/52aa645b-79d6-4f6f-910b-e1cff3f25a15"),
 	}
 	brandNotFound := testCase{
 		"Get Brand - Brand not found",
@@ -70,7 +74,7 @@ func TestHandlers(t *testing.T) {
 		"",
 		nil,
 		404,
-		`{"message": "brand not found"}`,
+		problemJSON(problemNotFound, "brand not found", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa"),
 	}
 	nonBrandReturnsNotFound := testCase{
 		"Get Brand - Other type returns not found",
@@ -79,7 +83,7 @@ func TestHandlers(t *testing.T) {
 		getPersonAsConcept,
 		nil,
 		404,
-		`{"message": "brand not found"}`,
+		problemJSON(problemNonBrandConcept, "concept is not a brand", "/brands/f92a4ca4-84f9-11e8-8f42-da24cd01f044"),
 	}
 	successfulRequest := testCase{
 		"Get Brand - Retrieves and transforms correctly",
@@ -105,11 +109,12 @@ func TestHandlers(t *testing.T) {
 		mockClient.resp = test.clientBody
 		mockClient.statusCode = test.clientCode
 		mockClient.err = test.clientError
-		bh := NewHandler(&mockClient, "localhost:8080/concepts")
+		bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
 		bh.RegisterHandlers(router)
 
 		rr := httptest.NewRecorder()
 		req, _ := http.NewRequest("GET", test.url, nil)
+		req.Header.Set("X-Request-Id", testTransactionID)
 
 		router.ServeHTTP(rr, req)
 		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
@@ -122,6 +127,325 @@ func TestHandlers(t *testing.T) {
 	}
 }
 
+func TestGetBrandVersions(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name                string
+		url                 string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand - Version 1 by default has only the first parent",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+			"",
+			200,
+			"application/json",
+			transformedMultipleParentBrandV1,
+		},
+		{
+			"Get Brand - Version 2 by query parameter has all the parents",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044?version=2",
+			"",
+			200,
+			"application/json; version=2",
+			transformedMultipleParentBrandV2,
+		},
+		{
+			"Get Brand - Version 2 by Accept header has all the parents",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+			"text/html, application/json; version=2",
+			200,
+			"application/json; version=2",
+			transformedMultipleParentBrandV2,
+		},
+		{
+			"Get Brand - Query parameter wins over Accept header",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044?version=1",
+			"application/json; version=2",
+			200,
+			"application/json",
+			transformedMultipleParentBrandV1,
+		},
+		{
+			"Get Brand - Unknown version results in error",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044?version=3",
+			"",
+			400,
+			problemMediaType,
+			problemJSON(problemInvalidRequest, "version must be a number between 1 and 2", "/brands/9636919c-838d-11e8-8f42-da24cd01f044?version=3"),
+		},
+	}
+
+	mockClient := mockHTTPClient{resp: getMultipleParentBrandAsConcept, statusCode: 200}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+
+	for _, test := range testCases {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.url, nil)
+		req.Header.Set("X-Request-Id", testTransactionID)
+		if test.accept != "" {
+			req.Header.Set("Accept", test.accept)
+		}
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		assert.Equal(t, test.expectedContentType, rr.Header().Get("Content-Type"), test.name+" failed: content types do not match!")
+		if rr.Code == 200 {
+			assert.Equal(t, transformBody(test.expectedBody), rr.Body.String(), test.name+" failed: status body does not match!")
+			continue
+		}
+		assert.Equal(t, test.expectedBody, rr.Body.String(), test.name+" failed: status body does not match!")
+	}
+}
+
+func TestGetBrandOptionalFields(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name                           string
+		url                            string
+		expectedAliases                []string
+		expectedAlternativeIdentifiers map[string][]string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand - Aliases and alternative identifiers are hidden by default",
+			"/brands/a806e270-edbc-423f-b8db-d21ae90e06c8",
+			nil,
+			nil,
+		},
+		{
+			"Get Brand - Aliases are shown when asked for",
+			"/brands/a806e270-edbc-423f-b8db-d21ae90e06c8?showAliases=true",
+			[]string{"SomeWonkyBrand", "AnotherAliasForABrand"},
+			nil,
+		},
+		{
+			"Get Brand - Alternative identifiers are shown when asked for",
+			"/brands/a806e270-edbc-423f-b8db-d21ae90e06c8?showAlternativeIdentifiers=true",
+			nil,
+			map[string][]string{
+				"TME":   {"NTlhNzEyMzMtZjBjZi00Y2U1LTg0ODUtZWVjNmEyYmU1NzQ2-QnJhbmRz"},
+				"uuids": {"5c7592a8-1f0c-11e4-b0cb-b2227cce2b54"},
+			},
+		},
+	}
+
+	mockClient := mockHTTPClient{resp: getBrandWithAliasesAsConcept, statusCode: 200}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+
+	for _, test := range testCases {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.url, nil)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, 200, rr.Code, test.name+" failed: status codes do not match!")
+		brand := Brand{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &brand), test.name+" failed: body is not a brand!")
+		assert.Equal(t, test.expectedAliases, brand.Aliases, test.name+" failed: aliases do not match!")
+		assert.Equal(t, test.expectedAlternativeIdentifiers, brand.AlternativeIdentifiers, test.name+" failed: alternative identifiers do not match!")
+	}
+}
+
+func TestGetBrandConditionalRequests(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	v1ETag := etagFor([]byte(transformBody(transformedMultipleParentBrandV1)))
+	v2ETag := etagFor([]byte(transformBody(transformedMultipleParentBrandV2)))
+
+	type testCase struct {
+		name                 string
+		url                  string
+		ifNoneMatch          string
+		ifModifiedSince      string
+		expectedCode         int
+		expectedETag         string
+		expectedLastModified string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand - Unconditional request has an ETag and Last-Modified",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+			"",
+			"",
+			200,
+			v1ETag,
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+		},
+		{
+			"Get Brand - Matching If-None-Match is not modified",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+			v1ETag,
+			"",
+			304,
+			v1ETag,
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+		},
+		{
+			"Get Brand - Weak If-None-Match in a list is not modified",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+			`"0123", W/` + v1ETag,
+			"",
+			304,
+			v1ETag,
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+		},
+		{
+			"Get Brand - Wildcard If-None-Match is not modified",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+			"*",
+			"",
+			304,
+			v1ETag,
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+		},
+		{
+			"Get Brand - Stale If-None-Match returns the brand",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+			`"0123"`,
+			"",
+			200,
+			v1ETag,
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+		},
+		{
+			"Get Brand - Each version has its own ETag",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044?version=2",
+			v1ETag,
+			"",
+			200,
+			v2ETag,
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+		},
+		{
+			"Get Brand - If-Modified-Since at Last-Modified is not modified",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+			"",
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+			304,
+			v1ETag,
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+		},
+		{
+			"Get Brand - If-Modified-Since before Last-Modified returns the brand",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+			"",
+			"Tue, 20 Oct 2015 07:28:00 GMT",
+			200,
+			v1ETag,
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+		},
+		{
+			"Get Brand - If-None-Match takes precedence over If-Modified-Since",
+			"/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+			`"0123"`,
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+			200,
+			v1ETag,
+			"Wed, 21 Oct 2015 07:28:00 GMT",
+		},
+	}
+
+	mockClient := mockHTTPClient{
+		resp:       getMultipleParentBrandAsConcept,
+		statusCode: 200,
+		header:     http.Header{"Last-Modified": []string{"Wed, 21 Oct 2015 07:28:00 GMT"}},
+	}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+
+	for _, test := range testCases {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.url, nil)
+		if test.ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", test.ifNoneMatch)
+		}
+		if test.ifModifiedSince != "" {
+			req.Header.Set("If-Modified-Since", test.ifModifiedSince)
+		}
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		assert.Equal(t, test.expectedETag, rr.Header().Get("ETag"), test.name+" failed: ETags do not match!")
+		assert.Equal(t, test.expectedLastModified, rr.Header().Get("Last-Modified"), test.name+" failed: Last-Modified does not match!")
+		if rr.Code == 304 {
+			assert.Empty(t, rr.Body.String(), test.name+" failed: not modified has a body!")
+		}
+	}
+}
+
+func TestRequestTimeout(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	RequestTimeout = 20 * time.Millisecond
+	defer func() { RequestTimeout = 0 }()
+
+	type testCase struct {
+		name         string
+		method       string
+		url          string
+		expectedCode int
+		expectedBody string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand - Slow upstream results in timeout",
+			"GET",
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			504,
+			problemJSON(problemUpstreamTimeout, "upstream did not respond in time", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa"),
+		},
+		{
+			"List Brands - Slow upstream results in timeout",
+			"GET",
+			"/brands",
+			504,
+			problemJSON(problemUpstreamTimeout, "upstream did not respond in time", "/brands"),
+		},
+		{
+			"Get Brand By Identifier - Slow upstream results in timeout",
+			"GET",
+			"/brands?identifierAuthority=TME&identifierValue=NTlhNzEyMzMtZjBjZi00Y2U1LTg0ODUtZWVjNmEyYmU1NzQ2-QnJhbmRz",
+			504,
+			problemJSON(problemUpstreamTimeout, "upstream did not respond in time", "/brands?identifierAuthority=TME&identifierValue=NTlhNzEyMzMtZjBjZi00Y2U1LTg0ODUtZWVjNmEyYmU1NzQ2-QnJhbmRz"),
+		},
+		{
+			"Get Brand Tree - Slow upstream results in timeout",
+			"GET",
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa/tree",
+			504,
+			problemJSON(problemUpstreamTimeout, "upstream did not respond in time", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa/tree"),
+		},
+	}
+
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&slowHTTPClient{}, "http://localhost:8080", "http://localhost:8080"))
+	bh.RegisterHandlers(router)
+
+	for _, test := range testCases {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest(test.method, test.url, nil)
+		req.Header.Set("X-Request-Id", testTransactionID)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		assert.Equal(t, test.expectedBody, rr.Body.String(), test.name+" failed: status body does not match!")
+	}
+}
+
 func transformBody(testBody string) string {
 	stripNewLines := strings.Replace(testBody, "\n", "", -1)
 	stripTabs := strings.Replace(stripNewLines, "\t", "", -1)
@@ -131,12 +455,13 @@ func transformBody(testBody string) string {
 type mockHTTPClient struct {
 	resp       string
 	statusCode int
+	header     http.Header
 	err        error
 }
 
 func (mhc *mockHTTPClient) Do(req *http.Request) (resp *http.Response, err error) {
 	cb := ioutil.NopCloser(bytes.NewReader([]byte(mhc.resp)))
-	return &http.Response{Body: cb, StatusCode: mhc.statusCode}, mhc.err
+	return &http.Response{Body: cb, StatusCode: mhc.statusCode, Header: mhc.header}, mhc.err
 }
 
 var getBasicBrandAsConcept = `{
@@ -250,3 +575,133 @@ var transformedCompleteBrand = `{
 		}
 	]
 }`
+
+var getMultipleParentBrandAsConcept = `{
+	"id": "http://www.ft.com/thing/9636919c-838d-11e8-8f42-da24cd01f044",
+	"apiUrl": "http://api.ft.com/concepts/9636919c-838d-11e8-8f42-da24cd01f044",
+	"prefLabel": "Lex",
+	"type": "http://www.ft.com/ontology/product/Brand",
+	"broaderConcepts": [
+		{
+			"concept": {
+				"id": "http://www.ft.com/thing/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54",
+				"apiUrl": "http://api.ft.com/concepts/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54",
+				"prefLabel": "Old father Lex",
+				"type": "http://www.ft.com/ontology/product/Brand"
+			}
+		},
+		{
+			"concept": {
+				"id": "http://www.ft.com/thing/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+				"apiUrl": "http://api.ft.com/concepts/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+				"prefLabel": "Not a brand",
+				"type": "http://www.ft.com/ontology/person/Person"
+			}
+		},
+		{
+			"concept": {
+				"id": "http://www.ft.com/thing/c0eab380-07fe-4672-a277-14ca51ef537e",
+				"apiUrl": "http://api.ft.com/concepts/c0eab380-07fe-4672-a277-14ca51ef537e",
+				"prefLabel": "Old mother Lex",
+				"type": "http://www.ft.com/ontology/product/Brand"
+			}
+		}
+	]
+}`
+
+var transformedMultipleParentBrandV1 = `{
+	"id":"http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044",
+	"apiUrl":"http://api.ft.com/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+	"types":[
+		"http://www.ft.com/ontology/core/Thing",
+		"http://www.ft.com/ontology/concept/Concept",
+		"http://www.ft.com/ontology/classification/Classification",
+		"http://www.ft.com/ontology/product/Brand"
+	],
+	"directType":"http://www.ft.com/ontology/product/Brand",
+	"prefLabel":"Lex",
+	"parentBrand":{
+		"id":"http://api.ft.com/things/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54",
+		"apiUrl":"http://api.ft.com/brands/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54",
+		"types":[
+			"http://www.ft.com/ontology/core/Thing",
+			"http://www.ft.com/ontology/concept/Concept",
+			"http://www.ft.com/ontology/classification/Classification",
+			"http://www.ft.com/ontology/product/Brand"
+		],
+		"directType":"http://www.ft.com/ontology/product/Brand",
+		"prefLabel":"Old father Lex"
+	}
+}`
+
+var transformedMultipleParentBrandV2 = `{
+	"id":"http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044",
+	"apiUrl":"http://api.ft.com/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+	"types":[
+		"http://www.ft.com/ontology/core/Thing",
+		"http://www.ft.com/ontology/concept/Concept",
+		"http://www.ft.com/ontology/classification/Classification",
+		"http://www.ft.com/ontology/product/Brand"
+	],
+	"directType":"http://www.ft.com/ontology/product/Brand",
+	"prefLabel":"Lex",
+	"parentBrands":[
+		{
+			"id":"http://api.ft.com/things/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54",
+			"apiUrl":"http://api.ft.com/brands/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54",
+			"types":[
+				"http://www.ft.com/ontology/core/Thing",
+				"http://www.ft.com/ontology/concept/Concept",
+				"http://www.ft.com/ontology/classification/Classification",
+				"http://www.ft.com/ontology/product/Brand"
+			],
+			"directType":"http://www.ft.com/ontology/product/Brand",
+			"prefLabel":"Old father Lex"
+		},{
+			"id":"http://api.ft.com/things/c0eab380-07fe-4672-a277-14ca51ef537e",
+			"apiUrl":"http://api.ft.com/brands/c0eab380-07fe-4672-a277-14ca51ef537e",
+			"types":[
+				"http://www.ft.com/ontology/core/Thing",
+				"http://www.ft.com/ontology/concept/Concept",
+				"http://www.ft.com/ontology/classification/Classification",
+				"http://www.ft.com/ontology/product/Brand"
+			],
+			"directType":"http://www.ft.com/ontology/product/Brand",
+			"prefLabel":"Old mother Lex"
+		}
+	]
+}`
+
+var getBrandWithAliasesAsConcept = `{
+	"id": "http://www.ft.com/thing/a806e270-edbc-423f-b8db-d21ae90e06c8",
+	"apiUrl": "http://api.ft.com/concepts/a806e270-edbc-423f-b8db-d21ae90e06c8",
+	"type": "http://www.ft.com/ontology/product/Brand",
+	"prefLabel": "validChildBrand",
+	"alternativeLabels": [
+		{"type": "http://www.w3.org/2008/05/skos-xl#altLabel", "value": "SomeWonkyBrand"},
+		{"type": "http://www.w3.org/2008/05/skos-xl#altLabel", "value": "AnotherAliasForABrand"}
+	],
+	"aliases": ["SomeWonkyBrand"],
+	"alternativeIdentifiers": {
+		"TME": ["NTlhNzEyMzMtZjBjZi00Y2U1LTg0ODUtZWVjNmEyYmU1NzQ2-QnJhbmRz"],
+		"uuids": ["5c7592a8-1f0c-11e4-b0cb-b2227cce2b54"]
+	}
+}`
+
+// slowHTTPClient never answers, failing like http.Client does once the request is cancelled
+type slowHTTPClient struct{}
+
+func (s *slowHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	<-req.Context().Done()
+	return nil, &url.Error{Op: req.Method, URL: req.URL.String(), Err: req.Context().Err()}
+}
+
+func TestGTGWhileDraining(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	mockClient := mockHTTPClient{resp: getBasicBrandAsConcept, statusCode: 200}
+	handler := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+	assert.Equal(t, gtg.Status{GoodToGo: true}, handler.GTG())
+
+	handler.Drain()
+	assert.Equal(t, gtg.Status{GoodToGo: false, Message: "draining connections before shutting down"}, handler.GTG())
+}
@@ -0,0 +1,60 @@
+package brands
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	logger "github.com/Financial-Times/go-logger"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+// identifierAuthorities maps the short authority names accepted by /brands to the authorities known to the concordances api
+var identifierAuthorities = map[string]string{
+	"TME":        "http://api.ft.com/system/FT-TME",
+	"UPP":        "http://api.ft.com/system/UPP",
+	"Smartlogic": "http://api.ft.com/system/SMARTLOGIC",
+}
+
+// GetBrandByIdentifier redirects to the canonical brand with the given alternative identifier,
+// e.g. /brands?identifierAuthority=TME&identifierValue=... or a legacy uuid with identifierAuthority=UPP
+func (h *BrandsHandler) GetBrandByIdentifier(w http.ResponseWriter, r *http.Request) {
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+	w.Header().Set("Cache-Control", CacheControlHeader)
+
+	query := r.URL.Query()
+	authority := query.Get("identifierAuthority")
+	if _, ok := identifierAuthorities[authority]; !ok {
+		writeProblem(w, r, problemInvalidRequest, fmt.Sprintf("identifierAuthority must be one of %s", strings.Join(supportedAuthorities(), ", ")))
+		return
+	}
+	identifierValue := query.Get("identifierValue")
+	if identifierValue == "" {
+		writeProblem(w, r, problemInvalidRequest, "identifierValue must be provided")
+		return
+	}
+
+	canonicalUUID, found, err := h.source.GetBrandUUIDByIdentifier(r.Context(), authority, identifierValue, transID)
+	if err != nil {
+		writeSourceError(w, r, err, "failed to return brand")
+		return
+	}
+	if !found {
+		writeProblem(w, r, problemNotFound, "brand not found")
+		return
+	}
+
+	logger.WithTransactionID(transID).WithUUID(canonicalUUID).Debug("serving redirect")
+	w.Header().Set("Location", "/brands/"+canonicalUUID)
+	w.WriteHeader(http.StatusMovedPermanently)
+}
+
+func supportedAuthorities() []string {
+	var authorities []string
+	for authority := range identifierAuthorities {
+		authorities = append(authorities, authority)
+	}
+	sort.Strings(authorities)
+	return authorities
+}
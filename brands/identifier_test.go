@@ -0,0 +1,115 @@
+package brands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBrandByIdentifier(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name             string
+		url              string
+		concordances     string
+		expectedCode     int
+		expectedLocation string
+		expectedBody     string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand By Identifier - TME identifier redirects to the brand",
+			"/brands?identifierAuthority=TME&identifierValue=NTlhNzEyMzMtZjBjZi00Y2U1LTg0ODUtZWVjNmEyYmU1NzQ2-QnJhbmRz",
+			concordanceFor("2d3e16e0-61cb-4322-8aff-3b01c59f4daa"),
+			301,
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			``,
+		},
+		{
+			"Get Brand By Identifier - Legacy uuid redirects to the canonical brand",
+			"/brands?identifierAuthority=UPP&identifierValue=5c7592a8-1f0c-11e4-b0cb-b2227cce2b54",
+			concordanceFor("f0a1b2c3-0000-4000-8000-000000000005"),
+			301,
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			``,
+		},
+		{
+			"Get Brand By Identifier - Identifier of a concept that is not a brand is not found",
+			"/brands?identifierAuthority=TME&identifierValue=UGVyc29u",
+			concordanceFor("f92a4ca4-84f9-11e8-8f42-da24cd01f044"),
+			404,
+			"",
+			problemJSON(problemNotFound, "brand not found", "/brands?identifierAuthority=TME&identifierValue=UGVyc29u"),
+		},
+		{
+			"Get Brand By Identifier - Unknown identifier is not found",
+			"/brands?identifierAuthority=TME&identifierValue=unknown",
+			`{"concordances": []}`,
+			404,
+			"",
+			problemJSON(problemNotFound, "brand not found", "/brands?identifierAuthority=TME&identifierValue=unknown"),
+		},
+		{
+			"Get Brand By Identifier - Unknown authority results in error",
+			"/brands?identifierAuthority=Wikidata&identifierValue=Q1",
+			`{"concordances": []}`,
+			400,
+			"",
+			problemJSON(problemInvalidRequest, "identifierAuthority must be one of Smartlogic, TME, UPP", "/brands?identifierAuthority=Wikidata&identifierValue=Q1"),
+		},
+		{
+			"Get Brand By Identifier - Missing value results in error",
+			"/brands?identifierAuthority=TME",
+			`{"concordances": []}`,
+			400,
+			"",
+			problemJSON(problemInvalidRequest, "identifierValue must be provided", "/brands?identifierAuthority=TME"),
+		},
+	}
+
+	for _, test := range testCases {
+		responses := map[string]string{"/concordances": test.concordances}
+		for path, body := range brandTreeAsConcepts {
+			responses[path] = body
+		}
+		for path, body := range brandAncestorsAsConcepts {
+			responses[path] = body
+		}
+		mockClient := mockRoutedHTTPClient{responses: responses}
+		router := mux.NewRouter()
+		bh := NewHandler(NewConceptsAPISource(&mockClient, "http://localhost:8080", "http://localhost:8080"))
+		bh.RegisterHandlers(router)
+
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.url, nil)
+		req.Header.Set("X-Request-Id", testTransactionID)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		assert.Equal(t, test.expectedLocation, rr.Header().Get("Location"), test.name+" failed: locations do not match!")
+		assert.Equal(t, test.expectedBody, rr.Body.String(), test.name+" failed: status body does not match!")
+	}
+}
+
+func concordanceFor(UUID string) string {
+	return `{
+		"concordances": [
+			{
+				"concept": {
+					"id": "http://api.ft.com/things/` + UUID + `",
+					"apiUrl": "http://api.ft.com/concepts/` + UUID + `"
+				},
+				"identifier": {
+					"authority": "http://api.ft.com/system/FT-TME",
+					"identifierValue": "NTlhNzEyMzMtZjBjZi00Y2U1LTg0ODUtZWVjNmEyYmU1NzQ2-QnJhbmRz"
+				}
+			}
+		]
+	}`
+}
@@ -0,0 +1,64 @@
+package brands
+
+const jsonLDMediaType = "application/ld+json"
+
+// brandJSONLDContext maps the fields of a brand to the FT ontology, using SKOS for its labels and its place
+// in the brand hierarchy
+var brandJSONLDContext = map[string]interface{}{
+	"ft":             "http://www.ft.com/ontology/",
+	"skos":           "http://www.w3.org/2004/02/skos/core#",
+	"xsd":            "http://www.w3.org/2001/XMLSchema#",
+	"prefLabel":      "skos:prefLabel",
+	"altLabel":       "skos:altLabel",
+	"broader":        map[string]string{"@id": "skos:broader", "@type": "@id"},
+	"narrower":       map[string]string{"@id": "skos:narrower", "@type": "@id"},
+	"apiUrl":         map[string]string{"@id": "ft:apiUrl", "@type": "@id"},
+	"isDeprecated":   map[string]string{"@id": "ft:isDeprecated", "@type": "xsd:boolean"},
+	"descriptionXML": "ft:descriptionXML",
+	"strapline":      "ft:strapline",
+	"imageUrl":       map[string]string{"@id": "ft:imageUrl", "@type": "@id"},
+}
+
+// BrandJSONLD is a brand as a JSON-LD document, typed with every type of its type hierarchy
+type BrandJSONLD struct {
+	Context        map[string]interface{} `json:"@context"`
+	ID             string                 `json:"@id"`
+	Types          []string               `json:"@type,omitempty"`
+	APIURL         string                 `json:"apiUrl,omitempty"`
+	PrefLabel      string                 `json:"prefLabel,omitempty"`
+	AltLabels      []string               `json:"altLabel,omitempty"`
+	IsDeprecated   bool                   `json:"isDeprecated,omitempty"`
+	DescriptionXML string                 `json:"descriptionXML,omitempty"`
+	Strapline      string                 `json:"strapline,omitempty"`
+	ImageURL       string                 `json:"imageUrl,omitempty"`
+	Broader        []string               `json:"broader,omitempty"`
+	Narrower       []string               `json:"narrower,omitempty"`
+}
+
+// jsonLD returns the brand as linked data. Every parent brand is broader than the brand, whatever the
+// version of the json response, and the aliases are alternative labels.
+func (b Brand) jsonLD() interface{} {
+	doc := BrandJSONLD{
+		Context:        brandJSONLDContext,
+		ID:             b.ID,
+		Types:          b.Types,
+		APIURL:         b.APIURL,
+		PrefLabel:      b.PrefLabel,
+		AltLabels:      b.Aliases,
+		IsDeprecated:   b.IsDeprecated,
+		DescriptionXML: b.DescriptionXML,
+		Strapline:      b.Strapline,
+		ImageURL:       b.ImageURL,
+	}
+	parents := b.Parents
+	if len(parents) == 0 && b.Parent != nil {
+		parents = []Thing{*b.Parent}
+	}
+	for _, parent := range parents {
+		doc.Broader = append(doc.Broader, parent.ID)
+	}
+	for _, child := range b.Children {
+		doc.Narrower = append(doc.Narrower, child.ID)
+	}
+	return doc
+}
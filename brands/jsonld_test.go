@@ -0,0 +1,121 @@
+package brands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBrandAsJSONLD(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name                string
+		accept              string
+		expectedContentType string
+		expectedBody        string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand - JSON-LD is returned when asked for",
+			"application/ld+json",
+			"application/ld+json",
+			transformedCompleteBrandAsJSONLD,
+		},
+		{
+			"Get Brand - JSON-LD is returned when asked for ahead of json",
+			"application/ld+json, application/json",
+			"application/ld+json",
+			transformedCompleteBrandAsJSONLD,
+		},
+		{
+			"Get Brand - Json is returned when asked for ahead of JSON-LD",
+			"application/json, application/ld+json",
+			"application/json",
+			transformedCompleteBrand,
+		},
+		{
+			"Get Brand - Json is returned when JSON-LD is refused",
+			"application/ld+json; q=0, */*",
+			"application/json",
+			transformedCompleteBrand,
+		},
+	}
+
+	for _, test := range testCases {
+		mockClient := mockHTTPClient{resp: getCompleteBrandAsConcept, statusCode: 200}
+		router := mux.NewRouter()
+		bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+		bh.RegisterHandlers(router)
+
+		req, _ := http.NewRequest("GET", "/brands/9636919c-838d-11e8-8f42-da24cd01f044", nil)
+		req.Header.Set("Accept", test.accept)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, test.name+" failed: status codes do not match!")
+		assert.Equal(t, test.expectedContentType, rr.Header().Get("Content-Type"), test.name+" failed: content types do not match!")
+		assert.JSONEq(t, test.expectedBody, rr.Body.String(), test.name+" failed: bodies do not match!")
+	}
+}
+
+func TestGetBrandWithMultipleParentsAsJSONLD(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	mockClient := mockHTTPClient{resp: getMultipleParentBrandAsConcept, statusCode: 200}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+
+	req, _ := http.NewRequest("GET", "/brands/9636919c-838d-11e8-8f42-da24cd01f044", nil)
+	req.Header.Set("Accept", "application/ld+json")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	doc := BrandJSONLD{}
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+	assert.Equal(t, []string{
+		"http://api.ft.com/things/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54",
+		"http://api.ft.com/things/c0eab380-07fe-4672-a277-14ca51ef537e",
+	}, doc.Broader, "every parent brand should be broader without asking for version 2")
+}
+
+var transformedCompleteBrandAsJSONLD = `{
+	"@context": {
+		"ft": "http://www.ft.com/ontology/",
+		"skos": "http://www.w3.org/2004/02/skos/core#",
+		"xsd": "http://www.w3.org/2001/XMLSchema#",
+		"prefLabel": "skos:prefLabel",
+		"altLabel": "skos:altLabel",
+		"broader": {"@id": "skos:broader", "@type": "@id"},
+		"narrower": {"@id": "skos:narrower", "@type": "@id"},
+		"apiUrl": {"@id": "ft:apiUrl", "@type": "@id"},
+		"isDeprecated": {"@id": "ft:isDeprecated", "@type": "xsd:boolean"},
+		"descriptionXML": "ft:descriptionXML",
+		"strapline": "ft:strapline",
+		"imageUrl": {"@id": "ft:imageUrl", "@type": "@id"}
+	},
+	"@id": "http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044",
+	"@type": [
+		"http://www.ft.com/ontology/core/Thing",
+		"http://www.ft.com/ontology/concept/Concept",
+		"http://www.ft.com/ontology/classification/Classification",
+		"http://www.ft.com/ontology/product/Brand"
+	],
+	"apiUrl": "http://api.ft.com/brands/9636919c-838d-11e8-8f42-da24cd01f044",
+	"prefLabel": "Lex",
+	"descriptionXML": "One brand to rule them all, one brand to find them, one brand to bring them all and in the darkness bind them",
+	"strapline": "Something",
+	"imageUrl": "www.imgur.com",
+	"broader": ["http://api.ft.com/things/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54"],
+	"narrower": [
+		"http://api.ft.com/things/0be232ac-841f-11e8-8f42-da24cd01f044",
+		"http://api.ft.com/things/c0eab380-07fe-4672-a277-14ca51ef537e"
+	]
+}`
@@ -0,0 +1,162 @@
+package brands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	logger "github.com/Financial-Times/go-logger"
+)
+
+const (
+	kafkaContentType = "application/vnd.kafka.v2+json"
+	kafkaJSONRecords = "application/vnd.kafka.json.v2+json"
+	// kafkaRequestTimeout is how long a single request to the kafka rest proxy can take
+	kafkaRequestTimeout = 30 * time.Second
+)
+
+// KafkaConsumer reads concept changes from a kafka topic through a Confluent kafka rest proxy, as a member of
+// a consumer group so that the changes are shared out between the instances of the group. Each instance of the
+// service should use its own group, as every instance has its own cache to evict changed brands from.
+// Offsets are committed by the proxy as the records are read.
+type KafkaConsumer struct {
+	client       httpClient
+	proxyURL     string
+	topic        string
+	group        string
+	pollInterval time.Duration
+	retryDelay   time.Duration
+}
+
+// NewKafkaConsumer reads the topic from the proxy at proxyURL, polling for new records every pollInterval when
+// there were none last time
+func NewKafkaConsumer(client httpClient, proxyURL string, topic string, group string, pollInterval time.Duration) *KafkaConsumer {
+	return &KafkaConsumer{
+		client:       client,
+		proxyURL:     strings.TrimSuffix(proxyURL, "/"),
+		topic:        topic,
+		group:        group,
+		pollInterval: pollInterval,
+		retryDelay:   10 * pollInterval,
+	}
+}
+
+type kafkaConsumerInstance struct {
+	InstanceID string `json:"instance_id"`
+	BaseURI    string `json:"base_uri"`
+}
+
+type kafkaRecord struct {
+	Topic     string          `json:"topic"`
+	Value     json.RawMessage `json:"value"`
+	Partition int             `json:"partition"`
+	Offset    int64           `json:"offset"`
+}
+
+// Consume joins the consumer group and delivers its changes until ctx is done. Failures to talk to the proxy are
+// logged and the group is joined again after a delay, so that eviction carries on once the proxy recovers.
+func (c *KafkaConsumer) Consume(ctx context.Context, handle func(ConceptChange)) error {
+	for {
+		err := c.consumeInstance(ctx, handle)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		logger.WithError(err).Warnf("failed to consume concept changes from %s, joining consumer group %s again in %v", c.topic, c.group, c.retryDelay)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.retryDelay):
+		}
+	}
+}
+
+// consumeInstance creates a consumer instance in the group, subscribes it to the topic and reads records with it
+// until reading fails or ctx is done. The instance is deleted on the way out.
+func (c *KafkaConsumer) consumeInstance(ctx context.Context, handle func(ConceptChange)) error {
+	instance := kafkaConsumerInstance{}
+	err := c.do(ctx, "POST", c.proxyURL+"/consumers/"+c.group, map[string]string{
+		"format":             "json",
+		"auto.offset.reset":  "latest",
+		"auto.commit.enable": "true",
+	}, &instance)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		// the instance is deleted even when ctx is done, so that the proxy does not hold on to it
+		deleteCtx, cancel := context.WithTimeout(context.Background(), kafkaRequestTimeout)
+		defer cancel()
+		if err := c.do(deleteCtx, "DELETE", instance.BaseURI, nil, nil); err != nil {
+			logger.WithError(err).Warnf("failed to delete kafka consumer instance %s", instance.InstanceID)
+		}
+	}()
+
+	if err = c.do(ctx, "POST", instance.BaseURI+"/subscription", map[string][]string{"topics": {c.topic}}, nil); err != nil {
+		return err
+	}
+	logger.Infof("consuming concept changes from %s as %s in consumer group %s", c.topic, instance.InstanceID, c.group)
+
+	for {
+		var records []kafkaRecord
+		if err = c.do(ctx, "GET", instance.BaseURI+"/records", nil, &records); err != nil {
+			return err
+		}
+		for _, record := range records {
+			var change ConceptChange
+			if err := json.Unmarshal(record.Value, &change); err != nil || change.UUID == "" {
+				logger.WithError(err).Warnf("ignoring concept change at offset %d of partition %d that is not a json object with a uuid", record.Offset, record.Partition)
+				continue
+			}
+			handle(change)
+		}
+		if len(records) > 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(c.pollInterval):
+		}
+	}
+}
+
+// do makes a request to the proxy with body encoded as json, if there is one, and decodes the json response into
+// result, if there is one. Anything but a 2xx response is an error.
+func (c *KafkaConsumer) do(ctx context.Context, method string, url string, body interface{}, result interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, kafkaRequestTimeout)
+	defer cancel()
+
+	var reqBody bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&reqBody).Encode(body); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, method, url, &reqBody)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", kafkaContentType)
+	req.Header.Set("Accept", kafkaContentType)
+	if method == "GET" {
+		req.Header.Set("Accept", kafkaJSONRecords)
+	}
+	req.Header.Set("User-Agent", "UPP public-brands-api")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned status %d", method, url, resp.StatusCode)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(result)
+}
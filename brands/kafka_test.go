@@ -0,0 +1,115 @@
+package brands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKafkaConsumer(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name              string
+		failedReads       int
+		expectedInstances int
+	}
+
+	testCases := []testCase{
+		{
+			"Kafka Consumer - Changes are read from the topic",
+			0,
+			1,
+		},
+		{
+			"Kafka Consumer - Consumer group is joined again after a failure",
+			1,
+			2,
+		},
+	}
+
+	for _, test := range testCases {
+		proxy := newFakeKafkaProxy(test.failedReads, `{"uuid": "`+lexUUID+`", "transactionId": "tid_test"}`, `"not a change"`, `{"uuid": "`+ftUUID+`"}`)
+		server := httptest.NewServer(proxy)
+		consumer := NewKafkaConsumer(http.DefaultClient, server.URL+"/", "ConceptChanges", "public-brands-api-test", time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		changes := make(chan ConceptChange)
+		done := make(chan error)
+		go func() { done <- consumer.Consume(ctx, func(change ConceptChange) { changes <- change }) }()
+
+		assert.Equal(t, ConceptChange{UUID: lexUUID, TransactionID: "tid_test"}, <-changes, test.name+" failed: first change does not match!")
+		assert.Equal(t, ConceptChange{UUID: ftUUID}, <-changes, test.name+" failed: second change does not match!")
+		cancel()
+		assert.Equal(t, context.Canceled, <-done, test.name+" failed: consume error does not match!")
+		server.Close()
+
+		proxy.Lock()
+		assert.Equal(t, test.expectedInstances, proxy.created, test.name+" failed: created consumer instances do not match!")
+		assert.Equal(t, test.expectedInstances, proxy.deleted, test.name+" failed: deleted consumer instances do not match!")
+		assert.Equal(t, []string{"ConceptChanges"}, proxy.topics, test.name+" failed: subscribed topics do not match!")
+		proxy.Unlock()
+	}
+}
+
+// fakeKafkaProxy is a kafka rest proxy whose consumer instances fail the first failedReads reads of records, and
+// then read values once between them before finding no more records
+type fakeKafkaProxy struct {
+	sync.Mutex
+	failedReads int
+	values      []string
+	created     int
+	deleted     int
+	topics      []string
+}
+
+func newFakeKafkaProxy(failedReads int, values ...string) *fakeKafkaProxy {
+	return &fakeKafkaProxy{failedReads: failedReads, values: values}
+}
+
+func (p *fakeKafkaProxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.Lock()
+	defer p.Unlock()
+	instanceURI := fmt.Sprintf("/consumers/public-brands-api-test/instances/%d", p.created)
+
+	switch {
+	case r.Method == "POST" && r.URL.Path == "/consumers/public-brands-api-test":
+		p.created++
+		w.Header().Set("Content-Type", kafkaContentType)
+		json.NewEncoder(w).Encode(kafkaConsumerInstance{
+			InstanceID: fmt.Sprintf("%d", p.created),
+			BaseURI:    "http://" + r.Host + fmt.Sprintf("/consumers/public-brands-api-test/instances/%d", p.created),
+		})
+	case r.Method == "POST" && r.URL.Path == instanceURI+"/subscription":
+		subscription := map[string][]string{}
+		json.NewDecoder(r.Body).Decode(&subscription)
+		p.topics = subscription["topics"]
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == "GET" && r.URL.Path == instanceURI+"/records" && r.Header.Get("Accept") == kafkaJSONRecords:
+		if p.failedReads > 0 {
+			p.failedReads--
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		var records []kafkaRecord
+		for i, value := range p.values {
+			records = append(records, kafkaRecord{Topic: p.topics[0], Value: json.RawMessage(value), Offset: int64(i)})
+		}
+		p.values = nil
+		w.Header().Set("Content-Type", kafkaJSONRecords)
+		json.NewEncoder(w).Encode(records)
+	case r.Method == "DELETE" && r.URL.Path == instanceURI:
+		p.deleted++
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
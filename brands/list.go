@@ -0,0 +1,207 @@
+package brands
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	logger "github.com/Financial-Times/go-logger"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+const (
+	defaultListLimit = 50
+	maxListLimit     = 500
+	sortByLabel      = "prefLabel"
+	sortByLabelDesc  = "-prefLabel"
+)
+
+// ListBrands returns a page of all brands sorted by prefLabel, from the brand list kept with the search index.
+// Pages are requested with the opaque cursor returned as nextCursor on the previous page.
+// When an alternative identifier is given the request is a lookup of that brand instead.
+func (h *BrandsHandler) ListBrands(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("identifierAuthority") != "" || query.Get("identifierValue") != "" {
+		h.GetBrandByIdentifier(w, r)
+		return
+	}
+
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+	w.Header().Set("Cache-Control", CacheControlHeader)
+
+	limit, err := parseListLimit(query.Get("limit"))
+	if err != nil {
+		writeProblem(w, r, problemInvalidRequest, err.Error())
+		return
+	}
+	sortOrder := query.Get("sort")
+	if sortOrder == "" {
+		sortOrder = sortByLabel
+	}
+	if sortOrder != sortByLabel && sortOrder != sortByLabelDesc {
+		writeProblem(w, r, problemInvalidRequest, fmt.Sprintf("sort must be one of %s, %s", sortByLabel, sortByLabelDesc))
+		return
+	}
+	var after *listCursor
+	if c := query.Get("cursor"); c != "" {
+		if after, err = decodeListCursor(c); err != nil {
+			writeProblem(w, r, problemInvalidRequest, "cursor is invalid")
+			return
+		}
+	}
+	includeDeprecated := query.Get("includeDeprecated") == "true"
+
+	things, err := h.listedBrands(r.Context(), transID)
+	if err != nil {
+		writeSourceError(w, r, err, "failed to return brands")
+		return
+	}
+
+	page := paginateBrands(things, includeDeprecated, sortOrder == sortByLabelDesc, after, limit)
+
+	if page.NextCursor != "" {
+		next := *r.URL
+		query := next.Query()
+		query.Set("cursor", page.NextCursor)
+		next.RawQuery = query.Encode()
+		w.Header().Set("Link", "<"+next.RequestURI()+`>; rel="next"`)
+	}
+	body, contentType, err := encode(r, page)
+	if err != nil {
+		logger.WithError(err).WithTransactionID(transID).Error("brand list could not be marshaled")
+		writeProblem(w, r, problemInternal, "brand list could not be marshaled")
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// listedBrands returns every brand from the in-process brand list, which is read from the brand source by the
+// first request if the search index has not been refreshed yet
+func (h *BrandsHandler) listedBrands(ctx context.Context, transID string) ([]Thing, error) {
+	if things, loaded := h.brandList.get(); loaded {
+		return things, nil
+	}
+	brands, err := h.source.GetBrands(ctx, transID)
+	if err != nil {
+		return nil, err
+	}
+	h.brandList.replace(brands)
+	things, _ := h.brandList.get()
+	return things, nil
+}
+
+// brandList is every brand, deprecated or not, kept in process alongside the search index so that pages of the
+// list are not each read from the brand source
+type brandList struct {
+	sync.RWMutex
+	things []Thing
+	loaded bool
+}
+
+func (l *brandList) replace(brands []Brand) {
+	things := make([]Thing, 0, len(brands))
+	for _, brand := range brands {
+		things = append(things, brand.Thing)
+	}
+	l.Lock()
+	defer l.Unlock()
+	l.things = things
+	l.loaded = true
+}
+
+func (l *brandList) get() ([]Thing, bool) {
+	l.RLock()
+	defer l.RUnlock()
+	return l.things, l.loaded
+}
+
+func paginateBrands(things []Thing, includeDeprecated bool, descending bool, after *listCursor, limit int) BrandList {
+	var filtered []Thing
+	for _, thing := range things {
+		if thing.IsDeprecated && !includeDeprecated {
+			continue
+		}
+		filtered = append(filtered, thing)
+	}
+
+	less := func(a, b listCursor) bool {
+		if descending {
+			return b.less(a)
+		}
+		return a.less(b)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		return less(cursorFor(filtered[i]), cursorFor(filtered[j]))
+	})
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(filtered), func(i int) bool {
+			return less(*after, cursorFor(filtered[i]))
+		})
+	}
+
+	page := BrandList{Brands: []Thing{}}
+	end := start + limit
+	if end > len(filtered) {
+		end = len(filtered)
+	}
+	page.Brands = append(page.Brands, filtered[start:end]...)
+	if end < len(filtered) {
+		page.NextCursor = cursorFor(filtered[end-1]).encode()
+	}
+	return page
+}
+
+// listCursor identifies the last brand on a page by its sort key
+type listCursor struct {
+	Label string `json:"l"`
+	ID    string `json:"i"`
+}
+
+func cursorFor(thing Thing) listCursor {
+	return listCursor{Label: strings.ToLower(thing.PrefLabel), ID: thing.ID}
+}
+
+func (c listCursor) less(other listCursor) bool {
+	if c.Label != other.Label {
+		return c.Label < other.Label
+	}
+	return c.ID < other.ID
+}
+
+func (c listCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeListCursor(cursor string) (*listCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+	c := listCursor{}
+	if err = json.Unmarshal(b, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func parseListLimit(limit string) (int, error) {
+	if limit == "" {
+		return defaultListLimit, nil
+	}
+	l, err := strconv.Atoi(limit)
+	if err != nil || l < 1 || l > maxListLimit {
+		return 0, fmt.Errorf("limit must be a number between 1 and %d", maxListLimit)
+	}
+	return l, nil
+}
@@ -0,0 +1,232 @@
+package brands
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListBrands(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name           string
+		url            string
+		clientCode     int
+		clientBody     string
+		clientError    error
+		expectedCode   int
+		expectedLabels []string
+		expectedNext   bool
+	}
+
+	testCases := []testCase{
+		{
+			"List Brands - Sorted by prefLabel without deprecated brands",
+			"/brands",
+			200,
+			brandListAsConcepts,
+			nil,
+			200,
+			[]string{"Alphaville", "FastFT", "Lex"},
+			false,
+		},
+		{
+			"List Brands - Includes deprecated brands when asked",
+			"/brands?includeDeprecated=true",
+			200,
+			brandListAsConcepts,
+			nil,
+			200,
+			[]string{"Alphaville", "Beyond Brics", "FastFT", "Lex"},
+			false,
+		},
+		{
+			"List Brands - Sorted by prefLabel descending",
+			"/brands?sort=-prefLabel",
+			200,
+			brandListAsConcepts,
+			nil,
+			200,
+			[]string{"Lex", "FastFT", "Alphaville"},
+			false,
+		},
+		{
+			"List Brands - First page has a next cursor",
+			"/brands?limit=2",
+			200,
+			brandListAsConcepts,
+			nil,
+			200,
+			[]string{"Alphaville", "FastFT"},
+			true,
+		},
+		{
+			"List Brands - Invalid limit results in error",
+			"/brands?limit=0",
+			200,
+			brandListAsConcepts,
+			nil,
+			400,
+			nil,
+			false,
+		},
+		{
+			"List Brands - Invalid cursor results in error",
+			"/brands?cursor=not-a-cursor",
+			200,
+			brandListAsConcepts,
+			nil,
+			400,
+			nil,
+			false,
+		},
+		{
+			"List Brands - Invalid sort results in error",
+			"/brands?sort=id",
+			200,
+			brandListAsConcepts,
+			nil,
+			400,
+			nil,
+			false,
+		},
+		{
+			"List Brands - Concepts API Error results in error",
+			"/brands",
+			503,
+			"",
+			errors.New("Downstream error"),
+			500,
+			nil,
+			false,
+		},
+	}
+
+	for _, test := range testCases {
+		mockClient := mockHTTPClient{resp: test.clientBody, statusCode: test.clientCode, err: test.clientError}
+		router := mux.NewRouter()
+		bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+		bh.RegisterHandlers(router)
+
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.url, nil)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		if rr.Code != 200 {
+			continue
+		}
+		page := BrandList{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page), test.name+" failed: body is not a brand list!")
+		assert.Equal(t, test.expectedLabels, labelsOf(page.Brands), test.name+" failed: brands do not match!")
+		assert.Equal(t, test.expectedNext, page.NextCursor != "", test.name+" failed: next cursor does not match!")
+	}
+}
+
+func TestListBrandsFollowsCursor(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	mockClient := countingHTTPClient{mockHTTPClient: mockHTTPClient{resp: brandListAsConcepts, statusCode: 200}}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+
+	var labels []string
+	url := "/brands?limit=1&includeDeprecated=true"
+	for pages := 0; url != "" && pages < 10; pages++ {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", url, nil)
+		router.ServeHTTP(rr, req)
+		assert.Equal(t, 200, rr.Code)
+
+		page := BrandList{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+		labels = append(labels, labelsOf(page.Brands)...)
+		url = ""
+		if page.NextCursor != "" {
+			url = "/brands?limit=1&includeDeprecated=true&cursor=" + page.NextCursor
+		}
+	}
+	assert.Equal(t, []string{"Alphaville", "Beyond Brics", "FastFT", "Lex"}, labels)
+	assert.Equal(t, 1, mockClient.calls, "every brand should only be read for the first page")
+}
+
+func TestListBrandsServesRefreshedList(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	mockClient := countingHTTPClient{mockHTTPClient: mockHTTPClient{resp: brandListAsConcepts, statusCode: 200}}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+	assert.NoError(t, bh.RefreshSearchIndex())
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/brands", nil)
+	router.ServeHTTP(rr, req)
+
+	page := BrandList{}
+	assert.Equal(t, 200, rr.Code)
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &page))
+	assert.Equal(t, []string{"Alphaville", "FastFT", "Lex"}, labelsOf(page.Brands))
+	assert.Equal(t, 1, mockClient.calls, "brand list should be served from the refreshed search index")
+}
+
+// countingHTTPClient answers as mockHTTPClient does and counts the requests made
+type countingHTTPClient struct {
+	mockHTTPClient
+	calls int
+}
+
+func (c *countingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.calls++
+	return c.mockHTTPClient.Do(req)
+}
+
+func labelsOf(things []Thing) []string {
+	var labels []string
+	for _, thing := range things {
+		labels = append(labels, thing.PrefLabel)
+	}
+	return labels
+}
+
+var brandListAsConcepts = `{
+	"concepts": [
+		{
+			"id": "http://www.ft.com/thing/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			"apiUrl": "http://api.ft.com/concepts/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			"type": "http://www.ft.com/ontology/product/Brand",
+			"prefLabel": "Lex"
+		},
+		{
+			"id": "http://www.ft.com/thing/5c7592a8-1f0c-11e4-b0cb-b2227cce2b54",
+			"apiUrl": "http://api.ft.com/concepts/5c7592a8-1f0c-11e4-b0cb-b2227cce2b54",
+			"type": "http://www.ft.com/ontology/product/Brand",
+			"prefLabel": "FastFT"
+		},
+		{
+			"id": "http://www.ft.com/thing/89d15f70-640d-11e4-9803-0800200c9a66",
+			"apiUrl": "http://api.ft.com/concepts/89d15f70-640d-11e4-9803-0800200c9a66",
+			"type": "http://www.ft.com/ontology/product/Brand",
+			"prefLabel": "Alphaville"
+		},
+		{
+			"id": "http://www.ft.com/thing/1b9c4d24-7fd5-41fa-9b7d-f4f5d5b15fb4",
+			"apiUrl": "http://api.ft.com/concepts/1b9c4d24-7fd5-41fa-9b7d-f4f5d5b15fb4",
+			"type": "http://www.ft.com/ontology/product/Brand",
+			"prefLabel": "Beyond Brics",
+			"isDeprecated": true
+		},
+		{
+			"id": "http://www.ft.com/thing/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+			"apiUrl": "http://api.ft.com/concepts/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+			"type": "http://www.ft.com/ontology/person/Person",
+			"prefLabel": "Not a brand"
+		}
+	]
+}`
@@ -1,5 +1,7 @@
 package brands
 
+import "time"
+
 // Thing is the base entity, all Public APIs should have these properties
 type Thing struct {
 	ID           string   `json:"id,omitempty"`
@@ -17,18 +19,80 @@ type Brand struct {
 	Strapline      string  `json:"strapline,omitempty"`
 	ImageURL       string  `json:"_imageUrl,omitempty"` // NB Temp hack
 	Parent         *Thing  `json:"parentBrand,omitempty"`
+	Parents        []Thing `json:"parentBrands,omitempty"`
 	Children       []Thing `json:"childBrands,omitempty"`
+
+	Aliases                []string            `json:"aliases,omitempty"`
+	AlternativeIdentifiers map[string][]string `json:"alternativeIdentifiers,omitempty"`
+
+	// LastModified is when the upstream last changed the brand, if it says
+	LastModified time.Time `json:"-"`
+	// Stale is set on a brand served from the cache because reading it again failed
+	Stale bool `json:"-"`
+}
+
+// forVersion returns the brand as it is represented in the requested version of the response.
+// Version 1 only has the first parent brand, version 2 has all of them.
+func (b Brand) forVersion(version int) Brand {
+	if version >= 2 {
+		b.Parent = nil
+	} else {
+		b.Parents = nil
+	}
+	return b
+}
+
+// withOptionalFields returns the brand with the aliases and alternative identifiers removed unless they were asked for
+func (b Brand) withOptionalFields(showAliases bool, showAlternativeIdentifiers bool) Brand {
+	if !showAliases {
+		b.Aliases = nil
+	}
+	if !showAlternativeIdentifiers {
+		b.AlternativeIdentifiers = nil
+	}
+	return b
+}
+
+// BrandTreeNode is a brand together with its descendants down to the requested depth
+type BrandTreeNode struct {
+	Thing
+	Children []BrandTreeNode `json:"childBrands,omitempty"`
+}
+
+// BrandAncestors are the parent brands of a brand, nearest first
+type BrandAncestors struct {
+	Ancestors []Thing `json:"ancestors"`
+}
+
+// SearchResults are the brands matching a search, best match first
+type SearchResults struct {
+	Brands []Thing `json:"brands"`
+}
+
+// BatchBrandRequest lists the uuids of the brands to look up in one go
+type BatchBrandRequest struct {
+	UUIDs []string `json:"uuids"`
+}
+
+// BatchBrandResult is the outcome of looking up a single uuid of a batch.
+// Brand is only set when the brand is found, CanonicalUUID only when the uuid redirects to another brand.
+type BatchBrandResult struct {
+	Status        string `json:"status"`
+	Brand         *Brand `json:"brand,omitempty"`
+	CanonicalUUID string `json:"canonicalUUID,omitempty"`
 }
 
 // NeoBrand is the same as Brand, but it receives an extra field and multiple parents.
 type NeoBrand struct {
 	NeoThing
-	DescriptionXML string     `json:"descriptionXML,omitempty"`
-	Strapline      string     `json:"strapline,omitempty"`
-	ImageURL       string     `json:"imageUrl,omitempty"` // NB Temp hack
-	Parent         NeoThing   `json:"parent,omitempty"`
-	Children       []NeoThing `json:"children,omitempty"`
-	Authority      string     `json:"authority,omitempty"`
+	DescriptionXML string      `json:"descriptionXML,omitempty"`
+	Strapline      string      `json:"strapline,omitempty"`
+	ImageURL       string      `json:"imageUrl,omitempty"` // NB Temp hack
+	Aliases        []string    `json:"aliases,omitempty"`
+	Parents        []NeoThing  `json:"parents,omitempty"`
+	Children       []NeoThing  `json:"children,omitempty"`
+	Sources        []NeoSource `json:"sources,omitempty"`
+	Authority      string      `json:"authority,omitempty"`
 }
 
 type NeoThing struct {
@@ -38,23 +102,62 @@ type NeoThing struct {
 	IsDeprecated bool     `json:"isDeprecated,omitempty"`
 }
 
+// NeoSource is one of the concepts concorded to a canonical brand in neo4j
+type NeoSource struct {
+	UUID           string `json:"uuid,omitempty"`
+	Authority      string `json:"authority,omitempty"`
+	AuthorityValue string `json:"authorityValue,omitempty"`
+}
+
 type ConceptApiResponse struct {
 	Concept
-	ImageURL       string           `json:"imageUrl,omitempty"`
-	DescriptionXML string           `json:"descriptionXML,omitempty"`
-	Strapline      string           `json:"strapline,omitempty"`
-	Broader        []RelatedConcept `json:"broaderConcepts,omitempty"`
-	Narrower       []RelatedConcept `json:"narrowerConcepts,omitempty"`
+	ImageURL               string              `json:"imageUrl,omitempty"`
+	DescriptionXML         string              `json:"descriptionXML,omitempty"`
+	Strapline              string              `json:"strapline,omitempty"`
+	Aliases                []string            `json:"aliases,omitempty"`
+	AlternativeIdentifiers map[string][]string `json:"alternativeIdentifiers,omitempty"`
+	Broader                []RelatedConcept    `json:"broaderConcepts,omitempty"`
+	Narrower               []RelatedConcept    `json:"narrowerConcepts,omitempty"`
 }
 
 type RelatedConcept struct {
-	Concept Concept `json:concept,omitempty`
+	Concept Concept `json:"concept,omitempty"`
 }
 
 type Concept struct {
-	ID           string `json:"id,omitempty"`
-	ApiURL       string `json:"apiUrl,omitempty"`
-	PrefLabel    string `json:"prefLabel,omitempty"`
-	Type         string `json:"type,omitempty"`
-	IsDeprecated bool   `json:"isDeprecated,omitempty"`
+	ID                string             `json:"id,omitempty"`
+	ApiURL            string             `json:"apiUrl,omitempty"`
+	PrefLabel         string             `json:"prefLabel,omitempty"`
+	Type              string             `json:"type,omitempty"`
+	IsDeprecated      bool               `json:"isDeprecated,omitempty"`
+	AlternativeLabels []AlternativeLabel `json:"alternativeLabels,omitempty"`
+}
+
+type AlternativeLabel struct {
+	Type  string `json:"type,omitempty"`
+	Value string `json:"value,omitempty"`
+}
+
+type ConceptSearchApiResponse struct {
+	Concepts []Concept `json:"concepts,omitempty"`
+}
+
+type ConcordancesApiResponse struct {
+	Concordances []Concordance `json:"concordances,omitempty"`
+}
+
+type Concordance struct {
+	Concept    Concept    `json:"concept,omitempty"`
+	Identifier Identifier `json:"identifier,omitempty"`
+}
+
+type Identifier struct {
+	Authority       string `json:"authority,omitempty"`
+	IdentifierValue string `json:"identifierValue,omitempty"`
+}
+
+// BrandList is a single page of brands, NextCursor is omitted on the last page
+type BrandList struct {
+	Brands     []Thing `json:"brands"`
+	NextCursor string  `json:"nextCursor,omitempty"`
 }
@@ -0,0 +1,240 @@
+package brands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Encoder writes response values as a single media type
+type Encoder struct {
+	// MediaType is the Content-Type of the encoded values
+	MediaType string
+	// Params must all be given on a media range of the Accept header for it to pick the encoder, so wildcards never do
+	Params map[string]string
+	// Versioned encoders are given the requested version of a brand, the others every parent of it
+	Versioned bool
+	Encode    func(w io.Writer, v interface{}) error
+}
+
+var (
+	jsonEncoder       = Encoder{MediaType: "application/json", Versioned: true, Encode: encodeJSON}
+	prettyJSONEncoder = Encoder{MediaType: "application/json", Params: map[string]string{"pretty": "true"}, Versioned: true, Encode: encodePrettyJSON}
+	jsonLDEncoder     = Encoder{MediaType: jsonLDMediaType, Encode: encodeJSONLD}
+	turtleEncoder     = Encoder{MediaType: turtleMediaType, Encode: encodeTurtle}
+	nTriplesEncoder   = Encoder{MediaType: nTriplesMediaType, Encode: encodeNTriples}
+)
+
+// brandEncoders are the formats a brand can be returned as, and listEncoders those a page of brands can be
+var (
+	brandEncoders = newEncoderRegistry(jsonEncoder, prettyJSONEncoder, jsonLDEncoder, turtleEncoder, nTriplesEncoder)
+	listEncoders  = newEncoderRegistry(jsonEncoder, prettyJSONEncoder, turtleEncoder, nTriplesEncoder)
+)
+
+// jsonLDDocument is implemented by values that can be encoded as JSON-LD
+type jsonLDDocument interface {
+	jsonLD() interface{}
+}
+
+// rdfResource is implemented by values that can be encoded as RDF triples
+type rdfResource interface {
+	triples() []triple
+}
+
+func encodeJSON(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+func encodePrettyJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func encodeJSONLD(w io.Writer, v interface{}) error {
+	doc, ok := v.(jsonLDDocument)
+	if !ok {
+		return fmt.Errorf("%T cannot be encoded as %s", v, jsonLDMediaType)
+	}
+	return json.NewEncoder(w).Encode(doc.jsonLD())
+}
+
+func encodeTurtle(w io.Writer, v interface{}) error {
+	resource, ok := v.(rdfResource)
+	if !ok {
+		return fmt.Errorf("%T cannot be encoded as %s", v, turtleMediaType)
+	}
+	return writeTurtle(w, resource.triples())
+}
+
+func encodeNTriples(w io.Writer, v interface{}) error {
+	resource, ok := v.(rdfResource)
+	if !ok {
+		return fmt.Errorf("%T cannot be encoded as %s", v, nTriplesMediaType)
+	}
+	return writeNTriples(w, resource.triples())
+}
+
+// encoderRegistry is the encoders an endpoint can respond with, in the order the api prefers them
+type encoderRegistry struct {
+	encoders []Encoder
+}
+
+func newEncoderRegistry(encoders ...Encoder) *encoderRegistry {
+	reg := &encoderRegistry{}
+	for _, e := range encoders {
+		reg.register(e)
+	}
+	return reg
+}
+
+func (reg *encoderRegistry) register(e Encoder) {
+	reg.encoders = append(reg.encoders, e)
+}
+
+// mediaTypes lists the media types of the registered encoders once each
+func (reg *encoderRegistry) mediaTypes() []string {
+	var mediaTypes []string
+	seen := map[string]bool{}
+	for _, e := range reg.encoders {
+		if !seen[e.MediaType] {
+			seen[e.MediaType] = true
+			mediaTypes = append(mediaTypes, e.MediaType)
+		}
+	}
+	return mediaTypes
+}
+
+// negotiate picks the encoder for an Accept header. Each encoder gets the quality of the most specific media range
+// that matches it, and the encoder with the highest quality wins. Ties go to the more specific match, then to the
+// media range given first, then to the order of the registry. An empty Accept header, or one that cannot be parsed
+// at all, accepts anything. ok is false when every encoder is refused.
+func (reg *encoderRegistry) negotiate(accept string) (encoder Encoder, ok bool) {
+	ranges := parseAccept(accept)
+	if len(ranges) == 0 {
+		return reg.encoders[0], true
+	}
+
+	best := -1
+	var bestQ float64
+	var bestSpecificity, bestPosition int
+	for i, e := range reg.encoders {
+		q, specificity, position := 0.0, -1, 0
+		for j, m := range ranges {
+			if s, matched := m.matches(e); matched && s > specificity {
+				q, specificity, position = m.q, s, j
+			}
+		}
+		if specificity < 0 || q <= 0 {
+			continue
+		}
+		if best < 0 || q > bestQ ||
+			(q == bestQ && specificity > bestSpecificity) ||
+			(q == bestQ && specificity == bestSpecificity && position < bestPosition) {
+			best, bestQ, bestSpecificity, bestPosition = i, q, specificity, position
+		}
+	}
+	if best < 0 {
+		return Encoder{}, false
+	}
+	return reg.encoders[best], true
+}
+
+// mediaRange is a single media range of an Accept header, with its quality
+type mediaRange struct {
+	mediaType string
+	params    map[string]string
+	q         float64
+}
+
+// parseAccept returns the media ranges of an Accept header, leaving out any that cannot be parsed
+func parseAccept(accept string) []mediaRange {
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+			delete(params, "q")
+		}
+		ranges = append(ranges, mediaRange{mediaType: mediaType, params: params, q: q})
+	}
+	return ranges
+}
+
+// matches is true when the media range accepts what the encoder writes. The specificity of the match is
+// 0 for */*, 1 for type/*, and 2 plus the number of encoder parameters for an exact media type.
+func (m mediaRange) matches(e Encoder) (specificity int, ok bool) {
+	switch {
+	case m.mediaType == "*/*":
+		return 0, len(e.Params) == 0
+	case strings.HasSuffix(m.mediaType, "/*"):
+		return 1, len(e.Params) == 0 && strings.HasPrefix(e.MediaType, strings.TrimSuffix(m.mediaType, "*"))
+	case m.mediaType != e.MediaType:
+		return 0, false
+	}
+	for name, value := range e.Params {
+		if m.params[name] != value {
+			return 0, false
+		}
+	}
+	return 2 + len(e.Params), true
+}
+
+type encoderKey struct{}
+
+// withContentNegotiation picks the encoder the response to the request is written with from encoders,
+// and responds with a 406 if the Accept header of the request refuses all of them
+func withContentNegotiation(encoders *encoderRegistry, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept")
+		encoder, ok := encoders.negotiate(r.Header.Get("Accept"))
+		if !ok {
+			writeProblem(w, r, problemNotAcceptable, "Accept header must allow one of "+strings.Join(encoders.mediaTypes(), ", "))
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), encoderKey{}, encoder)))
+	}
+}
+
+// encoderFor is the encoder negotiated for the request, which is json for endpoints that do not negotiate
+func encoderFor(r *http.Request) Encoder {
+	if encoder, ok := r.Context().Value(encoderKey{}).(Encoder); ok {
+		return encoder
+	}
+	return jsonEncoder
+}
+
+// encode writes v in the format negotiated for the request, so that nothing has been written to the
+// response yet if that fails
+func encode(r *http.Request, v interface{}) (body []byte, contentType string, err error) {
+	encoder := encoderFor(r)
+	buf := &bytes.Buffer{}
+	if err = encoder.Encode(buf, v); err != nil {
+		return nil, "", err
+	}
+	return buf.Bytes(), encoder.MediaType, nil
+}
+
+// writeEncoded responds with status and v in the format negotiated for the request
+func writeEncoded(w http.ResponseWriter, r *http.Request, status int, v interface{}) error {
+	body, contentType, err := encode(r, v)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(status)
+	_, err = w.Write(body)
+	return err
+}
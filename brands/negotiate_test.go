@@ -0,0 +1,150 @@
+package brands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateEncoder(t *testing.T) {
+	type testCase struct {
+		name              string
+		accept            string
+		expectedMediaType string
+		expectedPretty    bool
+		expectedOK        bool
+	}
+
+	testCases := []testCase{
+		{"Negotiate - No Accept header is json", "", "application/json", false, true},
+		{"Negotiate - Unparseable Accept header is json", "not a media type", "application/json", false, true},
+		{"Negotiate - Anything is json", "*/*", "application/json", false, true},
+		{"Negotiate - Exact media type", "text/turtle", "text/turtle", false, true},
+		{"Negotiate - Highest quality wins", "application/json; q=0.5, text/turtle; q=0.9", "text/turtle", false, true},
+		{"Negotiate - Equal quality goes to the first media range", "application/n-triples, text/turtle", "application/n-triples", false, true},
+		{"Negotiate - Specific media range overrides a wildcard", "text/turtle; q=0, text/*", "", false, false},
+		{"Negotiate - Wildcard quality applies to the rest", "application/json; q=0.1, */*; q=0.5", "application/ld+json", false, true},
+		{"Negotiate - Type wildcard", "text/*", "text/turtle", false, true},
+		{"Negotiate - Pretty json when asked for", "application/json; pretty=true", "application/json", true, true},
+		{"Negotiate - Pretty json is only picked by its parameter", "application/json; q=0.5, */*; q=0.1", "application/json", false, true},
+		{"Negotiate - Versioned json", "application/json; version=2", "application/json", false, true},
+		{"Negotiate - Invalid quality is ignored", "text/turtle; q=2, application/n-triples", "application/n-triples", false, true},
+		{"Negotiate - Nothing acceptable", "text/html, image/*", "", false, false},
+		{"Negotiate - Everything refused", "*/*; q=0", "", false, false},
+	}
+
+	for _, test := range testCases {
+		encoder, ok := brandEncoders.negotiate(test.accept)
+
+		assert.Equal(t, test.expectedOK, ok, test.name+" failed: acceptability does not match!")
+		assert.Equal(t, test.expectedMediaType, encoder.MediaType, test.name+" failed: media types do not match!")
+		assert.Equal(t, test.expectedPretty, encoder.Params["pretty"] == "true", test.name+" failed: prettiness does not match!")
+	}
+}
+
+func TestGetBrandNegotiatesErrors(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name                string
+		url                 string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand - Not found as json",
+			"/brands/99999999-1f0c-11e4-b0cb-b2227cce2b54",
+			"application/json",
+			404,
+			problemMediaType,
+			problemJSON(problemNotFound, "brand not found", "/brands/99999999-1f0c-11e4-b0cb-b2227cce2b54"),
+		},
+		{
+			"Get Brand - Not found as pretty json",
+			"/brands/99999999-1f0c-11e4-b0cb-b2227cce2b54",
+			"application/json; pretty=true",
+			404,
+			problemMediaType,
+			"{\n  \"type\": \"urn:ft:public-brands-api:problem:not-found\",\n  \"title\": \"The brand was not found\",\n  \"status\": 404,\n  \"detail\": \"brand not found\",\n  \"instance\": \"/brands/99999999-1f0c-11e4-b0cb-b2227cce2b54\",\n  \"transactionId\": \"tid_problemtest\",\n  \"code\": \"not-found\"\n}\n",
+		},
+		{
+			"Get Brand - Not found as JSON-LD",
+			"/brands/99999999-1f0c-11e4-b0cb-b2227cce2b54",
+			"application/ld+json",
+			404,
+			"application/ld+json",
+			`{"@context":{"detail":"http://www.w3.org/2000/01/rdf-schema#comment","title":"http://purl.org/dc/terms/title"},"type":"urn:ft:public-brands-api:problem:not-found","title":"The brand was not found","status":404,"detail":"brand not found","instance":"/brands/99999999-1f0c-11e4-b0cb-b2227cce2b54","transactionId":"tid_problemtest","code":"not-found"}` + "\n",
+		},
+		{
+			"Get Brand - Not found as N-Triples",
+			"/brands/99999999-1f0c-11e4-b0cb-b2227cce2b54",
+			"application/n-triples",
+			404,
+			"application/n-triples",
+			"_:problem <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <urn:ft:public-brands-api:problem:not-found> .\n" +
+				"_:problem <http://purl.org/dc/terms/title> \"The brand was not found\" .\n" +
+				"_:problem <http://www.w3.org/2000/01/rdf-schema#comment> \"brand not found\" .\n",
+		},
+		{
+			"Get Brand - Invalid uuid as Turtle",
+			"/brands/1234",
+			"text/turtle",
+			400,
+			"text/turtle",
+			"@prefix skos: <http://www.w3.org/2004/02/skos/core#> .\n\n" +
+				"_:problem a <urn:ft:public-brands-api:problem:invalid-uuid> ;\n" +
+				"\t<http://purl.org/dc/terms/title> \"The uuid is missing or invalid\" ;\n" +
+				"\t<http://www.w3.org/2000/01/rdf-schema#comment> \"uuid '1234' is either missing or invalid\" .\n",
+		},
+		{
+			"Get Brand - Not acceptable is problem json",
+			"/brands/1234",
+			"text/html",
+			406,
+			problemMediaType,
+			problemJSON(problemNotAcceptable, "Accept header must allow one of application/json, application/ld+json, text/turtle, application/n-triples", "/brands/1234"),
+		},
+	}
+
+	for _, test := range testCases {
+		mockClient := mockHTTPClient{resp: "", statusCode: 404}
+		router := mux.NewRouter()
+		bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+		bh.RegisterHandlers(router)
+
+		req, _ := http.NewRequest("GET", test.url, nil)
+		req.Header.Set("X-Request-Id", testTransactionID)
+		req.Header.Set("Accept", test.accept)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		assert.Equal(t, test.expectedContentType, rr.Header().Get("Content-Type"), test.name+" failed: content types do not match!")
+		assert.Equal(t, "Accept", rr.Header().Get("Vary"), test.name+" failed: vary does not match!")
+		assert.Equal(t, test.expectedBody, rr.Body.String(), test.name+" failed: bodies do not match!")
+	}
+}
+
+func TestGetBrandAsPrettyJSON(t *testing.T) {
+	mockClient := mockHTTPClient{resp: getBasicBrandAsConcept, statusCode: 200}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+
+	req, _ := http.NewRequest("GET", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", nil)
+	req.Header.Set("Accept", "application/json; pretty=true")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Body.String(), "\n  \"prefLabel\": \"Lex\"")
+}
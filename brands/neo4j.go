@@ -0,0 +1,279 @@
+package brands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
+	logger "github.com/Financial-Times/go-logger"
+	"github.com/Financial-Times/neo-model-utils-go/mapper"
+)
+
+// brandStatement reads a brand through the concordance of the uuid to its canonical brand, along with the
+// canonical parents and children of every concept concorded to it
+const brandStatement = `
+	MATCH (:Thing{uuid:$uuid})-[:EQUIVALENT_TO]->(canonical:Brand)
+	OPTIONAL MATCH (canonical)<-[:EQUIVALENT_TO]-(source:Thing)
+	OPTIONAL MATCH (canonical)<-[:EQUIVALENT_TO]-(:Thing)-[:HAS_PARENT]->(:Thing)-[:EQUIVALENT_TO]->(parent:Brand)
+	OPTIONAL MATCH (canonical)<-[:EQUIVALENT_TO]-(:Thing)<-[:HAS_PARENT]-(:Thing)-[:EQUIVALENT_TO]->(child:Brand)
+	RETURN {
+		id: canonical.prefUUID,
+		prefLabel: canonical.prefLabel,
+		isDeprecated: coalesce(canonical.isDeprecated, false),
+		descriptionXML: canonical.descriptionXML,
+		strapline: canonical.strapline,
+		imageUrl: canonical.imageUrl,
+		aliases: canonical.aliases,
+		sources: collect(DISTINCT {uuid: source.uuid, authority: source.authority, authorityValue: source.authorityValue}),
+		parents: collect(DISTINCT {id: parent.prefUUID, prefLabel: parent.prefLabel, isDeprecated: coalesce(parent.isDeprecated, false)}),
+		children: collect(DISTINCT {id: child.prefUUID, prefLabel: child.prefLabel, isDeprecated: coalesce(child.isDeprecated, false)})
+	}`
+
+const brandsStatement = `
+	MATCH (canonical:Brand)
+	WHERE exists(canonical.prefUUID)
+	RETURN {
+		id: canonical.prefUUID,
+		prefLabel: canonical.prefLabel,
+		isDeprecated: coalesce(canonical.isDeprecated, false),
+		aliases: canonical.aliases
+	}`
+
+// identifierStatements find the canonical brand of an alternative identifier, legacy uuids are the uuids of the
+// concorded concepts rather than an authority value
+var identifierStatements = map[string]string{
+	"UPP": `
+	MATCH (:Thing{uuid:$identifierValue})-[:EQUIVALENT_TO]->(canonical:Brand)
+	RETURN canonical.prefUUID`,
+	"TME": `
+	MATCH (:Thing{authority:$authority, authorityValue:$identifierValue})-[:EQUIVALENT_TO]->(canonical:Brand)
+	RETURN canonical.prefUUID`,
+	"Smartlogic": `
+	MATCH (:Thing{authority:$authority, authorityValue:$identifierValue})-[:EQUIVALENT_TO]->(canonical:Brand)
+	RETURN canonical.prefUUID`,
+}
+
+// Neo4jSource reads brands straight from neo4j through its transactional http endpoint,
+// so that brands can still be served while public-concepts-api is unavailable
+type Neo4jSource struct {
+	client httpClient
+	neoURL string
+}
+
+func NewNeo4jSource(client httpClient, neoURL string) *Neo4jSource {
+	return &Neo4jSource{
+		client: client,
+		neoURL: neoURL,
+	}
+}
+
+func (s *Neo4jSource) Checker() (string, error) {
+	if _, err := s.query(context.Background(), unwrapClient(s.client), "RETURN 1", nil, ""); err != nil {
+		return "", err
+	}
+	return "Connectivity to neo4j is ok", nil
+}
+
+func (s *Neo4jSource) HealthCheck() fthealth.Check {
+	return fthealth.Check{
+		ID:               "neo4j-check",
+		BusinessImpact:   "Unable to respond to Public Brands api requests",
+		Name:             "Check connectivity to neo4j",
+		PanicGuide:       "https://runbooks.in.ft.com/public-brands-api",
+		Severity:         2,
+		TechnicalSummary: "Not being able to communicate with neo4j means that requests for brands cannot be performed.",
+		Checker:          s.Checker,
+	}
+}
+
+func (s *Neo4jSource) GetBrand(ctx context.Context, UUID string, transID string) (Brand, string, bool, error) {
+	logger.WithTransactionID(transID).WithUUID(UUID).Debug("retrieving brand via neo4j")
+	start := time.Now()
+	rows, err := s.query(ctx, s.client, brandStatement, map[string]interface{}{"uuid": UUID}, transID)
+	if err != nil {
+		observeNeo4jCall(start, outcomeError)
+		return Brand{}, "", false, err
+	}
+	if len(rows) == 0 {
+		observeNeo4jCall(start, outcomeNotFound)
+		return Brand{}, "", false, nil
+	}
+
+	neoBrand := NeoBrand{}
+	if err = json.Unmarshal(rows[0], &neoBrand); err != nil {
+		observeNeo4jCall(start, outcomeError)
+		logger.WithError(err).WithTransactionID(transID).WithUUID(UUID).Error("failed to unmarshal brand from neo4j")
+		return Brand{}, "", false, err
+	}
+	if neoBrand.ID != UUID {
+		observeNeo4jCall(start, outcomeRedirect)
+	} else {
+		observeNeo4jCall(start, outcomeFound)
+	}
+	return mapNeoBrand(neoBrand), neoBrand.ID, true, nil
+}
+
+func (s *Neo4jSource) GetBrands(ctx context.Context, transID string) ([]Brand, error) {
+	logger.WithTransactionID(transID).Debug("retrieving brands via neo4j")
+	rows, err := s.query(ctx, s.client, brandsStatement, nil, transID)
+	if err != nil {
+		return nil, err
+	}
+
+	var brands []Brand
+	for _, row := range rows {
+		neoBrand := NeoBrand{}
+		if err = json.Unmarshal(row, &neoBrand); err != nil {
+			logger.WithError(err).WithTransactionID(transID).Error("failed to unmarshal brands from neo4j")
+			return nil, err
+		}
+		brands = append(brands, mapNeoBrand(neoBrand))
+	}
+	return brands, nil
+}
+
+func (s *Neo4jSource) GetBrandUUIDByIdentifier(ctx context.Context, authority string, identifierValue string, transID string) (string, bool, error) {
+	logger.WithTransactionID(transID).Debug("retrieving brand by identifier via neo4j")
+	parameters := map[string]interface{}{"authority": authority, "identifierValue": identifierValue}
+	rows, err := s.query(ctx, s.client, identifierStatements[authority], parameters, transID)
+	if err != nil || len(rows) == 0 {
+		return "", false, err
+	}
+
+	var canonicalUUID string
+	if err = json.Unmarshal(rows[0], &canonicalUUID); err != nil {
+		logger.WithError(err).WithTransactionID(transID).Error("failed to unmarshal brand uuid from neo4j")
+		return "", false, err
+	}
+	return canonicalUUID, true, nil
+}
+
+// query runs a single statement in its own transaction with client and returns the first column of every row
+func (s *Neo4jSource) query(ctx context.Context, client httpClient, statement string, parameters map[string]interface{}, transID string) ([]json.RawMessage, error) {
+	body, err := json.Marshal(neoTransactionRequest{Statements: []neoStatement{{Statement: statement, Parameters: parameters}}})
+	if err != nil {
+		return nil, err
+	}
+	request, err := http.NewRequestWithContext(ctx, "POST", s.neoURL+"/transaction/commit", bytes.NewReader(body))
+	if err != nil {
+		logger.WithError(err).WithTransactionID(transID).Error("failed to create request to neo4j")
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.Header.Set("X-Request-Id", transID)
+
+	resp, err := client.Do(request)
+	if err != nil {
+		logger.WithError(err).WithTransactionID(transID).Error("request to neo4j failed")
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("neo4j returned status %d", resp.StatusCode)
+		logger.WithError(err).WithTransactionID(transID).Error("request to neo4j failed")
+		return nil, err
+	}
+
+	result := neoTransactionResponse{}
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		logger.WithError(err).WithTransactionID(transID).Error("failed to unmarshal response from neo4j")
+		return nil, err
+	}
+	if len(result.Errors) > 0 {
+		err = errors.New(result.Errors[0].Code + ": " + result.Errors[0].Message)
+		logger.WithError(err).WithTransactionID(transID).Error("neo4j failed to run statement")
+		return nil, err
+	}
+
+	var rows []json.RawMessage
+	for _, r := range result.Results {
+		for _, data := range r.Data {
+			if len(data.Row) > 0 {
+				rows = append(rows, data.Row[0])
+			}
+		}
+	}
+	return rows, nil
+}
+
+type neoTransactionRequest struct {
+	Statements []neoStatement `json:"statements"`
+}
+
+type neoStatement struct {
+	Statement  string                 `json:"statement"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type neoTransactionResponse struct {
+	Results []struct {
+		Data []struct {
+			Row []json.RawMessage `json:"row"`
+		} `json:"data"`
+	} `json:"results"`
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func mapNeoBrand(neoBrand NeoBrand) Brand {
+	brand := Brand{
+		Thing:          convertNeoThing(neoBrand.NeoThing),
+		DescriptionXML: neoBrand.DescriptionXML,
+		Strapline:      neoBrand.Strapline,
+		ImageURL:       neoBrand.ImageURL,
+		Aliases:        neoBrand.Aliases,
+	}
+
+	for _, parent := range neoBrand.Parents {
+		// optional matches without a parent still collect a map of nulls
+		if parent.ID == "" {
+			continue
+		}
+		if brand.Parent == nil {
+			thing := convertNeoThing(parent)
+			brand.Parent = &thing
+		}
+		brand.Parents = append(brand.Parents, convertNeoThing(parent))
+	}
+	for _, child := range neoBrand.Children {
+		if child.ID == "" {
+			continue
+		}
+		brand.Children = append(brand.Children, convertNeoThing(child))
+	}
+
+	identifiers := map[string][]string{}
+	for _, source := range neoBrand.Sources {
+		if source.UUID != "" {
+			identifiers["uuids"] = append(identifiers["uuids"], source.UUID)
+		}
+		// uuid based authorities such as Smartlogic are already listed in uuids
+		if source.Authority != "" && source.AuthorityValue != "" && source.AuthorityValue != source.UUID {
+			identifiers[source.Authority] = append(identifiers[source.Authority], source.AuthorityValue)
+		}
+	}
+	if len(identifiers) > 0 {
+		brand.AlternativeIdentifiers = identifiers
+	}
+	return brand
+}
+
+func convertNeoThing(neoThing NeoThing) Thing {
+	return Thing{
+		ID:           thingsApiUrl + neoThing.ID,
+		APIURL:       brandsApiUrl + neoThing.ID,
+		Types:        mapper.FullTypeHierarchy(brandOntology),
+		DirectType:   brandOntology,
+		PrefLabel:    neoThing.PrefLabel,
+		IsDeprecated: neoThing.IsDeprecated,
+	}
+}
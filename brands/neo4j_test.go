@@ -0,0 +1,160 @@
+package brands
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBrandViaNeo4j(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name             string
+		url              string
+		clientCode       int
+		clientBody       string
+		clientError      error
+		expectedCode     int
+		expectedLocation string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand via Neo4j - Canonical brand",
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			200,
+			neoBrandResponse,
+			nil,
+			200,
+			"",
+		},
+		{
+			"Get Brand via Neo4j - Concorded uuid redirects to the canonical brand",
+			"/brands/5c7592a8-1f0c-11e4-b0cb-b2227cce2b54",
+			200,
+			neoBrandResponse,
+			nil,
+			301,
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+		},
+		{
+			"Get Brand via Neo4j - No rows is not found",
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			200,
+			`{"results": [{"columns": ["brand"], "data": []}], "errors": []}`,
+			nil,
+			404,
+			"",
+		},
+		{
+			"Get Brand via Neo4j - Statement errors result in error",
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			200,
+			`{"results": [], "errors": [{"code": "Neo.ClientError.Statement.SyntaxError", "message": "Invalid input"}]}`,
+			nil,
+			500,
+			"",
+		},
+		{
+			"Get Brand via Neo4j - Neo4j unavailable results in error",
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			503,
+			``,
+			nil,
+			500,
+			"",
+		},
+		{
+			"Get Brand via Neo4j - Neo4j unreachable results in error",
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			0,
+			``,
+			errors.New("connection refused"),
+			500,
+			"",
+		},
+	}
+
+	for _, test := range testCases {
+		mockClient := mockHTTPClient{resp: test.clientBody, statusCode: test.clientCode, err: test.clientError}
+		router := mux.NewRouter()
+		bh := NewHandler(NewNeo4jSource(&mockClient, "http://localhost:7474/db/data"))
+		bh.RegisterHandlers(router)
+
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.url, nil)
+		req.RequestURI = test.url
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		assert.Equal(t, test.expectedLocation, rr.Header().Get("Location"), test.name+" failed: locations do not match!")
+	}
+}
+
+func TestMapNeoBrand(t *testing.T) {
+	source := NewNeo4jSource(&mockHTTPClient{resp: neoBrandResponse, statusCode: 200}, "http://localhost:7474/db/data")
+	brand, canonicalUUID, found, err := source.GetBrand(context.Background(), "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "tid_test")
+
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", canonicalUUID)
+	assert.Equal(t, "http://api.ft.com/things/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", brand.ID)
+	assert.Equal(t, "http://api.ft.com/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", brand.APIURL)
+	assert.Equal(t, brandOntology, brand.DirectType)
+	assert.Equal(t, "Lex", brand.PrefLabel)
+	assert.Equal(t, "Lex description", brand.DescriptionXML)
+	assert.Equal(t, []string{"Lex Column"}, brand.Aliases)
+	assert.Equal(t, "FT", brand.Parent.PrefLabel)
+	assert.Equal(t, []string{"FT"}, labelsOf(brand.Parents))
+	assert.Equal(t, []string{"Lex Live"}, labelsOf(brand.Children))
+	assert.Equal(t, map[string][]string{
+		"uuids": {"2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "5c7592a8-1f0c-11e4-b0cb-b2227cce2b54"},
+		"TME":   {"NTlhNzEyMzMtZjBjZi00Y2U1LTg0ODUtZWVjNmEyYmU1NzQ2-QnJhbmRz"},
+	}, brand.AlternativeIdentifiers)
+}
+
+func TestGetBrandUUIDByIdentifierViaNeo4j(t *testing.T) {
+	mockClient := mockHTTPClient{resp: `{"results": [{"columns": ["canonical.prefUUID"], "data": [{"row": ["2d3e16e0-61cb-4322-8aff-3b01c59f4daa"]}]}], "errors": []}`, statusCode: 200}
+	source := NewNeo4jSource(&mockClient, "http://localhost:7474/db/data")
+
+	canonicalUUID, found, err := source.GetBrandUUIDByIdentifier(context.Background(), "TME", "NTlhNzEyMzMtZjBjZi00Y2U1LTg0ODUtZWVjNmEyYmU1NzQ2-QnJhbmRz", "tid_test")
+
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", canonicalUUID)
+}
+
+// neoBrandResponse has a parent that was not found, which neo4j still collects as a map of nulls
+var neoBrandResponse = `{
+	"results": [{
+		"columns": ["brand"],
+		"data": [{
+			"row": [{
+				"id": "2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+				"prefLabel": "Lex",
+				"isDeprecated": false,
+				"descriptionXML": "Lex description",
+				"aliases": ["Lex Column"],
+				"sources": [
+					{"uuid": "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "authority": "Smartlogic", "authorityValue": "2d3e16e0-61cb-4322-8aff-3b01c59f4daa"},
+					{"uuid": "5c7592a8-1f0c-11e4-b0cb-b2227cce2b54", "authority": "TME", "authorityValue": "NTlhNzEyMzMtZjBjZi00Y2U1LTg0ODUtZWVjNmEyYmU1NzQ2-QnJhbmRz"}
+				],
+				"parents": [
+					{"id": "dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54", "prefLabel": "FT", "isDeprecated": false},
+					{"id": null, "prefLabel": null, "isDeprecated": false}
+				],
+				"children": [
+					{"id": "e363dfb8-f6d9-4f2c-beba-5162b334272b", "prefLabel": "Lex Live", "isDeprecated": false}
+				]
+			}]
+		}]
+	}],
+	"errors": []
+}`
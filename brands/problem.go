@@ -0,0 +1,111 @@
+package brands
+
+import (
+	"net/http"
+
+	logger "github.com/Financial-Times/go-logger"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+const problemMediaType = "application/problem+json"
+
+// problemTypeBase is prefixed to the code of a problem to make its type uri
+const problemTypeBase = "urn:ft:public-brands-api:problem:"
+
+// problemKind is one of the ways a request can fail. Its code is stable, so clients can rely on it
+// where the detail of the problem is meant for people.
+type problemKind struct {
+	code   string
+	title  string
+	status int
+}
+
+var (
+	problemInvalidUUID         = problemKind{"invalid-uuid", "The uuid is missing or invalid", http.StatusBadRequest}
+	problemInvalidRequest      = problemKind{"invalid-request", "The request is invalid", http.StatusBadRequest}
+	problemRequestTooLarge     = problemKind{"request-too-large", "The request is too large", http.StatusRequestEntityTooLarge}
+	problemNotFound            = problemKind{"not-found", "The brand was not found", http.StatusNotFound}
+	problemNonBrandConcept     = problemKind{"non-brand-concept", "The concept is not a brand", http.StatusNotFound}
+	problemNotAcceptable       = problemKind{"not-acceptable", "No acceptable format", http.StatusNotAcceptable}
+	problemInternal            = problemKind{"internal-error", "The brand could not be returned", http.StatusInternalServerError}
+	problemUpstreamFailure     = problemKind{"upstream-failure", "The upstream api failed", http.StatusInternalServerError}
+	problemUpstreamUnavailable = problemKind{"upstream-unavailable", "The upstream api is unavailable", http.StatusServiceUnavailable}
+	problemUpstreamTimeout     = problemKind{"upstream-timeout", "The upstream api timed out", http.StatusGatewayTimeout}
+	problemBrokenHierarchy     = problemKind{"broken-hierarchy", "The brand hierarchy is broken", http.StatusInternalServerError}
+	problemSearchNotReady      = problemKind{"search-not-ready", "Brand search is not ready", http.StatusServiceUnavailable}
+)
+
+// problem is the body of an error response, as described by RFC 7807
+type problem struct {
+	Type          string `json:"type"`
+	Title         string `json:"title"`
+	Status        int    `json:"status"`
+	Detail        string `json:"detail,omitempty"`
+	Instance      string `json:"instance,omitempty"`
+	TransactionID string `json:"transactionId,omitempty"`
+	Code          string `json:"code"`
+	// DirectType is the type of a concept that was asked for as a brand but is not one, when asked for
+	DirectType string `json:"directType,omitempty"`
+}
+
+func newProblem(r *http.Request, kind problemKind, detail string) problem {
+	return problem{
+		Type:          problemTypeBase + kind.code,
+		Title:         kind.title,
+		Status:        kind.status,
+		Detail:        detail,
+		Instance:      r.URL.RequestURI(),
+		TransactionID: transactionidutils.GetTransactionIDFromRequest(r),
+		Code:          kind.code,
+	}
+}
+
+func (p problem) jsonLD() interface{} {
+	return struct {
+		Context map[string]string `json:"@context"`
+		problem
+	}{
+		Context: map[string]string{"title": dcTitle, "detail": rdfsComment},
+		problem: p,
+	}
+}
+
+func (p problem) triples() []triple {
+	t := []triple{
+		{subject: "_:problem", predicate: rdfType, object: p.Type},
+		{subject: "_:problem", predicate: dcTitle, object: p.Title, literal: true},
+	}
+	if p.Detail != "" {
+		t = append(t, triple{subject: "_:problem", predicate: rdfsComment, object: p.Detail, literal: true})
+	}
+	return t
+}
+
+// writeProblem responds with a problem of the kind in the format negotiated for the request, which is
+// application/problem+json rather than application/json when json is negotiated
+func writeProblem(w http.ResponseWriter, r *http.Request, kind problemKind, detail string) {
+	writeProblemDetails(w, r, newProblem(r, kind, detail))
+}
+
+// writeProblemDetails responds with p as writeProblem does, for problems with more to say than their detail
+func writeProblemDetails(w http.ResponseWriter, r *http.Request, p problem) {
+	entry := logger.WithTransactionID(p.TransactionID).WithField("problem", p.Code)
+	if p.Status >= http.StatusInternalServerError {
+		entry.Error(p.Detail)
+	} else {
+		entry.Info(p.Detail)
+	}
+
+	body, contentType, err := encode(r, p)
+	if err != nil {
+		logger.WithError(err).WithTransactionID(p.TransactionID).Error("problem could not be encoded")
+		w.WriteHeader(p.Status)
+		return
+	}
+	if contentType == jsonEncoder.MediaType {
+		contentType = problemMediaType
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(p.Status)
+	w.Write(body)
+}
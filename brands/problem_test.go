@@ -0,0 +1,138 @@
+package brands
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// testTransactionID is sent as the X-Request-Id of requests whose problem responses are compared
+const testTransactionID = "tid_problemtest"
+
+// problemJSON is the application/problem+json body of a problem for a request sent with testTransactionID
+func problemJSON(kind problemKind, detail string, instance string) string {
+	body, _ := json.Marshal(problem{
+		Type:          problemTypeBase + kind.code,
+		Title:         kind.title,
+		Status:        kind.status,
+		Detail:        detail,
+		Instance:      instance,
+		TransactionID: testTransactionID,
+		Code:          kind.code,
+	})
+	return string(body) + "\n"
+}
+
+func TestProblemIsEscaped(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	mockClient := mockHTTPClient{resp: getBasicBrandAsConcept, statusCode: 200}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+
+	req, _ := http.NewRequest("GET", `/brands/"},"x":"<y>`, nil)
+	req.Header.Set("X-Request-Id", testTransactionID)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{
+		"type": "urn:ft:public-brands-api:problem:invalid-uuid",
+		"title": "The uuid is missing or invalid",
+		"status": 400,
+		"detail": "uuid '\"},\"x\":\"<y>' is either missing or invalid",
+		"instance": "/brands/%22%7D,%22x%22:%22%3Cy%3E",
+		"transactionId": "tid_problemtest",
+		"code": "invalid-uuid"
+	}`, rr.Body.String())
+}
+
+func TestNonBrandConceptProblem(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name               string
+		url                string
+		clientBody         string
+		expectedDetail     string
+		expectedDirectType string
+		expectedLink       string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand - Non brand concept",
+			"/brands/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+			getPersonAsConcept,
+			"concept is not a brand",
+			"",
+			"",
+		},
+		{
+			"Get Brand - Non brand concept with its direct type links to people",
+			"/brands/f92a4ca4-84f9-11e8-8f42-da24cd01f044?showDirectType=true",
+			getPersonAsConcept,
+			"concept is a http://www.ft.com/ontology/person/Person rather than a brand",
+			"http://www.ft.com/ontology/person/Person",
+			`</people/f92a4ca4-84f9-11e8-8f42-da24cd01f044>; rel="alternate"`,
+		},
+		{
+			"Get Brand - Non brand concept with its direct type links to organisations",
+			"/brands/f92a4ca4-84f9-11e8-8f42-da24cd01f044?showDirectType=true",
+			getOrganisationAsConcept,
+			"concept is a http://www.ft.com/ontology/company/PublicCompany rather than a brand",
+			"http://www.ft.com/ontology/company/PublicCompany",
+			`</organisations/f92a4ca4-84f9-11e8-8f42-da24cd01f044>; rel="alternate"`,
+		},
+		{
+			"Get Brand - Non brand concept with its direct type links to things",
+			"/brands/f92a4ca4-84f9-11e8-8f42-da24cd01f044?showDirectType=true",
+			getTopicAsConcept,
+			"concept is a http://www.ft.com/ontology/Topic rather than a brand",
+			"http://www.ft.com/ontology/Topic",
+			`</things/f92a4ca4-84f9-11e8-8f42-da24cd01f044>; rel="alternate"`,
+		},
+	}
+
+	for _, test := range testCases {
+		mockClient := mockHTTPClient{resp: test.clientBody, statusCode: 200}
+		router := mux.NewRouter()
+		bh := NewHandler(NewCachingSource(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"), 10, 30*time.Second, time.Minute, metrics.NewRegistry()))
+		bh.RegisterHandlers(router)
+
+		req, _ := http.NewRequest("GET", test.url, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		var p problem
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &p), test.name+" failed: body is not a problem!")
+		assert.Equal(t, http.StatusNotFound, rr.Code, test.name+" failed: status codes do not match!")
+		assert.Equal(t, "non-brand-concept", p.Code, test.name+" failed: codes do not match!")
+		assert.Equal(t, test.expectedDetail, p.Detail, test.name+" failed: details do not match!")
+		assert.Equal(t, test.expectedDirectType, p.DirectType, test.name+" failed: direct types do not match!")
+		assert.Equal(t, test.expectedLink, rr.Header().Get("Link"), test.name+" failed: links do not match!")
+	}
+}
+
+var getOrganisationAsConcept = `{
+	"id": "http://www.ft.com/thing/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+	"apiUrl": "http://api.ft.com/concepts/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+	"type": "http://www.ft.com/ontology/company/PublicCompany",
+	"prefLabel": "Not a brand either"
+}`
+
+var getTopicAsConcept = `{
+	"id": "http://www.ft.com/thing/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+	"apiUrl": "http://api.ft.com/concepts/f92a4ca4-84f9-11e8-8f42-da24cd01f044",
+	"type": "http://www.ft.com/ontology/Topic",
+	"prefLabel": "Nor a brand"
+}`
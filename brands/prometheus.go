@@ -0,0 +1,85 @@
+package brands
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const (
+	outcomeFound    = "found"
+	outcomeNotFound = "not-found"
+	outcomeRedirect = "redirect"
+	outcomeNonBrand = "non-brand"
+	outcomeError    = "error"
+)
+
+// upstreamBuckets are the upper bounds in seconds of the latency histograms for brand requests to the backends
+var upstreamBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// The go-metrics registry remains in use for the graphite metrics, these are only kept for /metrics
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "public_brands_api_http_requests_total",
+		Help: "Requests served by the api, by route and status code.",
+	}, []string{"route", "code"})
+	conceptsAPIDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "public_brands_api_concepts_api_request_duration_seconds",
+		Help:    "Latency of brand requests to public-concepts-api, by outcome.",
+		Buckets: upstreamBuckets,
+	}, []string{"outcome"})
+	neo4jDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "public_brands_api_neo4j_request_duration_seconds",
+		Help:    "Latency of brand requests to neo4j, by outcome.",
+		Buckets: upstreamBuckets,
+	}, []string{"outcome"})
+	nonBrandConcepts = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "public_brands_api_non_brand_concepts_total",
+		Help: "Requested concepts that public-concepts-api knows but are not brands.",
+	})
+)
+
+// MetricsHandler serves the request counts and upstream latencies in the Prometheus text exposition format
+var MetricsHandler = promhttp.Handler()
+
+// withRequestMetrics counts the requests served by next under the route template, rather than the requested path,
+// so that the number of series does not grow with the uuids requested
+func withRequestMetrics(route string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		httpRequests.WithLabelValues(route, strconv.Itoa(sw.status)).Inc()
+	})
+}
+
+// statusWriter remembers the status code written to the ResponseWriter it wraps
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// observeConceptsAPICall records how long a brand request to public-concepts-api took since start, and what came of it
+func observeConceptsAPICall(start time.Time, outcome string) {
+	conceptsAPIDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+	if outcome == outcomeNonBrand {
+		nonBrandConcepts.Inc()
+	}
+}
+
+// observeNeo4jCall records how long a brand request to neo4j took since start, and what came of it
+func observeNeo4jCall(start time.Time, outcome string) {
+	neo4jDuration.WithLabelValues(outcome).Observe(time.Since(start).Seconds())
+}
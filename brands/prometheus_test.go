@@ -0,0 +1,169 @@
+package brands
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConceptsAPIMetrics(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name            string
+		clientCode      int
+		clientBody      string
+		clientError     error
+		expectedCode    string
+		expectedOutcome string
+	}
+
+	testCases := []testCase{
+		{
+			"Metrics - Brand is found",
+			200,
+			getBasicBrandAsConcept,
+			nil,
+			"200",
+			outcomeFound,
+		},
+		{
+			"Metrics - Brand is not found",
+			404,
+			"",
+			nil,
+			"404",
+			outcomeNotFound,
+		},
+		{
+			"Metrics - Brand is redirected",
+			200,
+			getRedirectedBrand,
+			nil,
+			"301",
+			outcomeRedirect,
+		},
+		{
+			"Metrics - Concept is not a brand",
+			200,
+			getPersonAsConcept,
+			nil,
+			"404",
+			outcomeNonBrand,
+		},
+		{
+			"Metrics - Concepts API fails",
+			503,
+			"",
+			errors.New("Downstream error"),
+			"500",
+			outcomeError,
+		},
+	}
+
+	for _, test := range testCases {
+		mockClient := mockHTTPClient{resp: test.clientBody, statusCode: test.clientCode, err: test.clientError}
+		router := mux.NewRouter()
+		bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+		bh.RegisterHandlers(router)
+
+		requests := testutil.ToFloat64(httpRequests.WithLabelValues("/brands/{uuid}", test.expectedCode))
+		calls := observations(conceptsAPIDuration, test.expectedOutcome)
+		nonBrands := testutil.ToFloat64(nonBrandConcepts)
+
+		req, _ := http.NewRequest("GET", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, requests+1, testutil.ToFloat64(httpRequests.WithLabelValues("/brands/{uuid}", test.expectedCode)), test.name+" failed: requests do not match!")
+		assert.Equal(t, calls+1, observations(conceptsAPIDuration, test.expectedOutcome), test.name+" failed: concepts api calls do not match!")
+		if test.expectedOutcome == outcomeNonBrand {
+			nonBrands++
+		}
+		assert.Equal(t, nonBrands, testutil.ToFloat64(nonBrandConcepts), test.name+" failed: non-brand concepts do not match!")
+	}
+}
+
+func TestNeo4jMetrics(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name            string
+		url             string
+		clientCode      int
+		clientBody      string
+		expectedOutcome string
+	}
+
+	testCases := []testCase{
+		{
+			"Metrics - Brand is found in neo4j",
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			200,
+			neoBrandResponse,
+			outcomeFound,
+		},
+		{
+			"Metrics - Brand is redirected by neo4j",
+			"/brands/5c7592a8-1f0c-11e4-b0cb-b2227cce2b54",
+			200,
+			neoBrandResponse,
+			outcomeRedirect,
+		},
+		{
+			"Metrics - Brand is not found in neo4j",
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			200,
+			`{"results": [{"columns": ["brand"], "data": []}], "errors": []}`,
+			outcomeNotFound,
+		},
+		{
+			"Metrics - Neo4j fails",
+			"/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			503,
+			"",
+			outcomeError,
+		},
+	}
+
+	for _, test := range testCases {
+		mockClient := mockHTTPClient{resp: test.clientBody, statusCode: test.clientCode}
+		router := mux.NewRouter()
+		bh := NewHandler(NewNeo4jSource(&mockClient, "http://localhost:7474/db/data"))
+		bh.RegisterHandlers(router)
+
+		calls := observations(neo4jDuration, test.expectedOutcome)
+
+		req, _ := http.NewRequest("GET", test.url, nil)
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		assert.Equal(t, calls+1, observations(neo4jDuration, test.expectedOutcome), test.name+" failed: neo4j calls do not match!")
+	}
+}
+
+func TestMetricsHandler(t *testing.T) {
+	httpRequests.WithLabelValues("/brands/{uuid}", "200").Inc()
+	conceptsAPIDuration.WithLabelValues(outcomeFound).Observe(0.05)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	MetricsHandler.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `public_brands_api_http_requests_total{code="200",route="/brands/{uuid}"}`)
+	assert.Contains(t, rr.Body.String(), `public_brands_api_concepts_api_request_duration_seconds_bucket{outcome="found",le="0.1"}`)
+}
+
+// observations is the number of values observed by the series of the histogram with the label values
+func observations(histogram *prometheus.HistogramVec, labelValues ...string) uint64 {
+	metric := &dto.Metric{}
+	histogram.WithLabelValues(labelValues...).(prometheus.Histogram).Write(metric)
+	return metric.GetHistogram().GetSampleCount()
+}
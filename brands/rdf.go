@@ -0,0 +1,135 @@
+package brands
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	turtleMediaType   = "text/turtle"
+	nTriplesMediaType = "application/n-triples"
+
+	rdfType       = "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+	rdfsComment   = "http://www.w3.org/2000/01/rdf-schema#comment"
+	dcTitle       = "http://purl.org/dc/terms/title"
+	skosNamespace = "http://www.w3.org/2004/02/skos/core#"
+	skosPrefLabel = skosNamespace + "prefLabel"
+	skosBroader   = skosNamespace + "broader"
+	skosNarrower  = skosNamespace + "narrower"
+)
+
+// triple is a single RDF statement about a brand, its object is either an IRI or a plain string literal.
+// Subjects starting with _: are blank nodes rather than IRIs.
+type triple struct {
+	subject   string
+	predicate string
+	object    string
+	literal   bool
+}
+
+// thingTriples states the types and prefLabel of a thing
+func thingTriples(t Thing) []triple {
+	var triples []triple
+	for _, typ := range t.Types {
+		triples = append(triples, triple{subject: t.ID, predicate: rdfType, object: typ})
+	}
+	if t.PrefLabel != "" {
+		triples = append(triples, triple{subject: t.ID, predicate: skosPrefLabel, object: t.PrefLabel, literal: true})
+	}
+	return triples
+}
+
+// triples states the types and prefLabel of the brand, and that it is narrower than each of its parent brands
+// and broader than each of its child brands
+func (b Brand) triples() []triple {
+	triples := thingTriples(b.Thing)
+	parents := b.Parents
+	if len(parents) == 0 && b.Parent != nil {
+		parents = []Thing{*b.Parent}
+	}
+	for _, parent := range parents {
+		triples = append(triples, triple{subject: b.ID, predicate: skosBroader, object: parent.ID})
+	}
+	for _, child := range b.Children {
+		triples = append(triples, triple{subject: b.ID, predicate: skosNarrower, object: child.ID})
+	}
+	return triples
+}
+
+// triples states the types and prefLabel of every brand on the page
+func (l BrandList) triples() []triple {
+	var triples []triple
+	for _, thing := range l.Brands {
+		triples = append(triples, thingTriples(thing)...)
+	}
+	return triples
+}
+
+func writeNTriples(w io.Writer, triples []triple) error {
+	for _, t := range triples {
+		if _, err := fmt.Fprintf(w, "%s %s %s .\n", rdfSubject(t.subject), rdfIRI(t.predicate), t.rdfObject()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeTurtle groups the triples of each subject into a single statement, which assumes the triples of a
+// subject are next to each other as they are for brands and pages of brands
+func writeTurtle(w io.Writer, triples []triple) error {
+	var b strings.Builder
+	b.WriteString("@prefix skos: <" + skosNamespace + "> .\n")
+	for i, t := range triples {
+		switch {
+		case i > 0 && t.subject == triples[i-1].subject && t.predicate == triples[i-1].predicate:
+			b.WriteString(", ")
+		case i > 0 && t.subject == triples[i-1].subject:
+			b.WriteString(" ;\n\t" + turtlePredicate(t.predicate) + " ")
+		default:
+			if i > 0 {
+				b.WriteString(" .\n")
+			}
+			b.WriteString("\n" + rdfSubject(t.subject) + " " + turtlePredicate(t.predicate) + " ")
+		}
+		b.WriteString(t.rdfObject())
+	}
+	if len(triples) > 0 {
+		b.WriteString(" .\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func turtlePredicate(predicate string) string {
+	if predicate == rdfType {
+		return "a"
+	}
+	if strings.HasPrefix(predicate, skosNamespace) {
+		return "skos:" + strings.TrimPrefix(predicate, skosNamespace)
+	}
+	return rdfIRI(predicate)
+}
+
+func (t triple) rdfObject() string {
+	if t.literal {
+		return `"` + rdfLiteralEscaper.Replace(t.object) + `"`
+	}
+	return rdfIRI(t.object)
+}
+
+var rdfLiteralEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// rdfIRI escapes the characters that cannot appear in an IRI reference, which should never be in brand ids
+var rdfIRIEscaper = strings.NewReplacer("<", "%3C", ">", "%3E", `"`, "%22", " ", "%20", "{", "%7B", "}", "%7D", "|", "%7C", `\`, "%5C", "^", "%5E", "`", "%60")
+
+func rdfSubject(subject string) string {
+	if strings.HasPrefix(subject, "_:") {
+		return subject
+	}
+	return rdfIRI(subject)
+}
+
+func rdfIRI(iri string) string {
+	return "<" + rdfIRIEscaper.Replace(iri) + ">"
+}
@@ -0,0 +1,181 @@
+package brands
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBrandAsRDF(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name                string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+		expectedBody        string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand - Turtle is returned when asked for",
+			"text/turtle",
+			200,
+			"text/turtle",
+			transformedCompleteBrandAsTurtle,
+		},
+		{
+			"Get Brand - N-Triples are returned when asked for",
+			"application/n-triples",
+			200,
+			"application/n-triples",
+			transformedCompleteBrandAsNTriples,
+		},
+		{
+			"Get Brand - Turtle is returned for any text",
+			"text/html; q=0, text/*",
+			200,
+			"text/turtle",
+			transformedCompleteBrandAsTurtle,
+		},
+		{
+			"Get Brand - Unsupported media type is not acceptable",
+			"text/html",
+			406,
+			problemMediaType,
+			problemJSON(problemNotAcceptable, "Accept header must allow one of application/json, application/ld+json, text/turtle, application/n-triples", "/brands/9636919c-838d-11e8-8f42-da24cd01f044"),
+		},
+	}
+
+	for _, test := range testCases {
+		mockClient := mockHTTPClient{resp: getCompleteBrandAsConcept, statusCode: 200}
+		router := mux.NewRouter()
+		bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+		bh.RegisterHandlers(router)
+
+		req, _ := http.NewRequest("GET", "/brands/9636919c-838d-11e8-8f42-da24cd01f044", nil)
+		req.Header.Set("X-Request-Id", testTransactionID)
+		req.Header.Set("Accept", test.accept)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		assert.Equal(t, test.expectedContentType, rr.Header().Get("Content-Type"), test.name+" failed: content types do not match!")
+		assert.Equal(t, test.expectedBody, rr.Body.String(), test.name+" failed: bodies do not match!")
+	}
+}
+
+func TestGetBrandWithMultipleParentsAsRDF(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	for _, accept := range []string{"text/turtle", "application/n-triples"} {
+		mockClient := mockHTTPClient{resp: getMultipleParentBrandAsConcept, statusCode: 200}
+		router := mux.NewRouter()
+		bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+		bh.RegisterHandlers(router)
+
+		req, _ := http.NewRequest("GET", "/brands/9636919c-838d-11e8-8f42-da24cd01f044", nil)
+		req.Header.Set("Accept", accept)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code, accept+" failed: status codes do not match!")
+		assert.Contains(t, rr.Body.String(), "<http://api.ft.com/things/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54>", accept+" failed: first parent is not broader!")
+		assert.Contains(t, rr.Body.String(), "<http://api.ft.com/things/c0eab380-07fe-4672-a277-14ca51ef537e>", accept+" failed: second parent is not broader without asking for version 2!")
+	}
+}
+
+func TestListBrandsAsRDF(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name                string
+		url                 string
+		accept              string
+		expectedCode        int
+		expectedContentType string
+		expectedLink        string
+		expectedBody        string
+	}
+
+	testCases := []testCase{
+		{
+			"List Brands - N-Triples link to the next page",
+			"/brands?limit=1",
+			"application/n-triples",
+			200,
+			"application/n-triples",
+			`</brands?cursor=eyJsIjoiYWxwaGF2aWxsZSIsImkiOiJodHRwOi8vYXBpLmZ0LmNvbS90aGluZ3MvODlkMTVmNzAtNjQwZC0xMWU0LTk4MDMtMDgwMDIwMGM5YTY2In0&limit=1>; rel="next"`,
+			alphavilleAsNTriples,
+		},
+		{
+			"List Brands - Unsupported media type is not acceptable",
+			"/brands",
+			"application/ld+json",
+			406,
+			problemMediaType,
+			"",
+			problemJSON(problemNotAcceptable, "Accept header must allow one of application/json, text/turtle, application/n-triples", "/brands"),
+		},
+	}
+
+	for _, test := range testCases {
+		mockClient := mockHTTPClient{resp: brandListAsConcepts, statusCode: 200}
+		router := mux.NewRouter()
+		bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+		bh.RegisterHandlers(router)
+
+		req, _ := http.NewRequest("GET", test.url, nil)
+		req.Header.Set("X-Request-Id", testTransactionID)
+		req.Header.Set("Accept", test.accept)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		assert.Equal(t, test.expectedContentType, rr.Header().Get("Content-Type"), test.name+" failed: content types do not match!")
+		assert.Equal(t, test.expectedLink, rr.Header().Get("Link"), test.name+" failed: links do not match!")
+		assert.Equal(t, test.expectedBody, rr.Body.String(), test.name+" failed: bodies do not match!")
+	}
+}
+
+func TestRDFLiteralsAreEscaped(t *testing.T) {
+	triples := thingTriples(Thing{ID: "http://api.ft.com/things/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", PrefLabel: "Lex \"the column\"\n\\"})
+
+	nTriples, turtle := &strings.Builder{}, &strings.Builder{}
+	writeNTriples(nTriples, triples)
+	writeTurtle(turtle, triples)
+
+	assert.Equal(t, `<http://api.ft.com/things/2d3e16e0-61cb-4322-8aff-3b01c59f4daa> <http://www.w3.org/2004/02/skos/core#prefLabel> "Lex \"the column\"\n\\" .`+"\n", nTriples.String())
+	assert.Contains(t, turtle.String(), `skos:prefLabel "Lex \"the column\"\n\\" .`)
+}
+
+var transformedCompleteBrandAsTurtle = `@prefix skos: <http://www.w3.org/2004/02/skos/core#> .
+
+<http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044> a <http://www.ft.com/ontology/core/Thing>, <http://www.ft.com/ontology/concept/Concept>, <http://www.ft.com/ontology/classification/Classification>, <http://www.ft.com/ontology/product/Brand> ;
+	skos:prefLabel "Lex" ;
+	skos:broader <http://api.ft.com/things/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54> ;
+	skos:narrower <http://api.ft.com/things/0be232ac-841f-11e8-8f42-da24cd01f044>, <http://api.ft.com/things/c0eab380-07fe-4672-a277-14ca51ef537e> .
+`
+
+var transformedCompleteBrandAsNTriples = `<http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.ft.com/ontology/core/Thing> .
+<http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.ft.com/ontology/concept/Concept> .
+<http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.ft.com/ontology/classification/Classification> .
+<http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.ft.com/ontology/product/Brand> .
+<http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044> <http://www.w3.org/2004/02/skos/core#prefLabel> "Lex" .
+<http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044> <http://www.w3.org/2004/02/skos/core#broader> <http://api.ft.com/things/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54> .
+<http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044> <http://www.w3.org/2004/02/skos/core#narrower> <http://api.ft.com/things/0be232ac-841f-11e8-8f42-da24cd01f044> .
+<http://api.ft.com/things/9636919c-838d-11e8-8f42-da24cd01f044> <http://www.w3.org/2004/02/skos/core#narrower> <http://api.ft.com/things/c0eab380-07fe-4672-a277-14ca51ef537e> .
+`
+
+var alphavilleAsNTriples = `<http://api.ft.com/things/89d15f70-640d-11e4-9803-0800200c9a66> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.ft.com/ontology/core/Thing> .
+<http://api.ft.com/things/89d15f70-640d-11e4-9803-0800200c9a66> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.ft.com/ontology/concept/Concept> .
+<http://api.ft.com/things/89d15f70-640d-11e4-9803-0800200c9a66> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.ft.com/ontology/classification/Classification> .
+<http://api.ft.com/things/89d15f70-640d-11e4-9803-0800200c9a66> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <http://www.ft.com/ontology/product/Brand> .
+<http://api.ft.com/things/89d15f70-640d-11e4-9803-0800200c9a66> <http://www.w3.org/2004/02/skos/core#prefLabel> "Alphaville" .
+`
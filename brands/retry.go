@@ -0,0 +1,96 @@
+package brands
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"time"
+
+	logger "github.com/Financial-Times/go-logger"
+	"github.com/rcrowley/go-metrics"
+)
+
+const retryBaseDelay = 100 * time.Millisecond
+
+// RetryingClient retries GET requests that fail with a network error or a 502, 503 or 504 from the upstream.
+// Attempts are spaced by an exponential backoff with full jitter, and are not started once maxAttempts have
+// been made or the next attempt would start after budget has passed since the first one.
+type RetryingClient struct {
+	client      httpClient
+	maxAttempts int
+	budget      time.Duration
+	baseDelay   time.Duration
+	retries     metrics.Counter
+	now         func() time.Time
+}
+
+func NewRetryingClient(client httpClient, maxAttempts int, budget time.Duration, registry metrics.Registry) *RetryingClient {
+	return &RetryingClient{
+		client:      client,
+		maxAttempts: maxAttempts,
+		budget:      budget,
+		baseDelay:   retryBaseDelay,
+		retries:     metrics.GetOrRegisterCounter("brands.upstream.retries", registry),
+		now:         time.Now,
+	}
+}
+
+// Unwrap returns the client requests are made with, for requests that should not be retried
+func (c *RetryingClient) Unwrap() httpClient {
+	return c.client
+}
+
+func (c *RetryingClient) Do(req *http.Request) (*http.Response, error) {
+	if req.Method != "GET" {
+		return c.client.Do(req)
+	}
+
+	transID := req.Header.Get("X-Request-Id")
+	start := c.now()
+	for attempt := 1; ; attempt++ {
+		resp, err := c.client.Do(req)
+		if !isRetryable(resp, err) || attempt >= c.maxAttempts {
+			return resp, err
+		}
+
+		var delay time.Duration
+		if backoff := int64(c.baseDelay) << uint(attempt-1); backoff > 0 {
+			delay = time.Duration(rand.Int63n(backoff))
+		}
+		if c.now().Add(delay).Sub(start) > c.budget {
+			logger.WithTransactionID(transID).Warnf("not retrying request to %s, it would take longer than the retry budget of %s", req.URL, c.budget)
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(ioutil.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		c.retries.Inc(1)
+		logger.WithError(err).WithTransactionID(transID).Warnf("retrying request to %s in %s, attempt %d of %d failed", req.URL, delay, attempt, c.maxAttempts)
+		timer := time.NewTimer(delay)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryable is true for network errors and for statuses that a later attempt could succeed with.
+// Running out of time, the client going away or an open circuit breaker are not worth retrying.
+func isRetryable(resp *http.Response, err error) bool {
+	if err != nil {
+		var openErr *CircuitOpenError
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded) && !errors.As(err, &openErr)
+	}
+	switch resp.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
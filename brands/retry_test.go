@@ -0,0 +1,126 @@
+package brands
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/rcrowley/go-metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryingClient(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name             string
+		method           string
+		upstream         []int
+		budget           time.Duration
+		expectedStatus   int
+		expectedUpstream int
+		expectedRetries  int64
+	}
+
+	// an upstream status of 0 is a network error, which has no status
+	testCases := []testCase{
+		{
+			"Retrying Client - Success is not retried",
+			"GET",
+			[]int{200},
+			time.Minute,
+			200,
+			1,
+			0,
+		},
+		{
+			"Retrying Client - Bad gateway is retried",
+			"GET",
+			[]int{502, 200},
+			time.Minute,
+			200,
+			2,
+			1,
+		},
+		{
+			"Retrying Client - Network errors are retried",
+			"GET",
+			[]int{0, 200},
+			time.Minute,
+			200,
+			2,
+			1,
+		},
+		{
+			"Retrying Client - Gives up after the maximum attempts",
+			"GET",
+			[]int{503, 504, 503, 200},
+			time.Minute,
+			503,
+			3,
+			2,
+		},
+		{
+			"Retrying Client - Gives up when the budget would be exceeded",
+			"GET",
+			[]int{503, 200},
+			0,
+			503,
+			1,
+			0,
+		},
+		{
+			"Retrying Client - Internal server errors are not retried",
+			"GET",
+			[]int{500, 200},
+			time.Minute,
+			500,
+			1,
+			0,
+		},
+		{
+			"Retrying Client - Not found is not retried",
+			"GET",
+			[]int{404, 200},
+			time.Minute,
+			404,
+			1,
+			0,
+		},
+		{
+			"Retrying Client - Only GET is retried",
+			"POST",
+			[]int{503, 200},
+			time.Minute,
+			503,
+			1,
+			0,
+		},
+	}
+
+	for _, test := range testCases {
+		upstream := &scriptedHTTPClient{statuses: test.upstream}
+		registry := metrics.NewRegistry()
+		client := NewRetryingClient(upstream, 3, test.budget, registry)
+		client.baseDelay = time.Millisecond
+		// the clock moves on a millisecond every time it is read, so that a budget of 0 is always exceeded
+		now := time.Now()
+		client.now = func() time.Time {
+			now = now.Add(time.Millisecond)
+			return now
+		}
+
+		req, _ := http.NewRequest(test.method, "http://localhost:8080/concepts/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", nil)
+		req.Header.Set("X-Request-Id", "tid_test")
+		resp, err := client.Do(req)
+
+		status := 0
+		if err == nil {
+			status = resp.StatusCode
+		}
+		assert.Equal(t, test.expectedStatus, status, test.name+" failed: statuses do not match!")
+		assert.Equal(t, test.expectedUpstream, upstream.calls, test.name+" failed: upstream calls do not match!")
+		assert.Equal(t, test.expectedRetries, metrics.GetOrRegisterCounter("brands.upstream.retries", registry).Count(), test.name+" failed: retries do not match!")
+	}
+}
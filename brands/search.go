@@ -0,0 +1,257 @@
+package brands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	logger "github.com/Financial-Times/go-logger"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+)
+
+const (
+	defaultSearchLimit = 10
+	maxSearchLimit     = 50
+	noMatch            = -1
+)
+
+// SearchBrands returns the brands whose prefLabel or aliases best match the q parameter.
+// Searches are answered from the in-process search index rather than the brand source.
+func (h *BrandsHandler) SearchBrands(w http.ResponseWriter, r *http.Request) {
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", CacheControlHeader)
+
+	query := r.URL.Query()
+	q := strings.ToLower(strings.TrimSpace(query.Get("q")))
+	if q == "" {
+		writeProblem(w, r, problemInvalidRequest, "q must be provided")
+		return
+	}
+	limit := defaultSearchLimit
+	if l := query.Get("limit"); l != "" {
+		var err error
+		if limit, err = strconv.Atoi(l); err != nil || limit < 1 || limit > maxSearchLimit {
+			writeProblem(w, r, problemInvalidRequest, fmt.Sprintf("limit must be a number between 1 and %d", maxSearchLimit))
+			return
+		}
+	}
+
+	brands, ready := h.searchIndex.search(q, limit)
+	if !ready {
+		writeProblem(w, r, problemSearchNotReady, "brand search is not ready yet")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(SearchResults{Brands: brands}); err != nil {
+		logger.WithError(err).WithTransactionID(transID).Error("brand search results could not be marshaled")
+	}
+}
+
+// RefreshSearchIndex replaces the search index and the brand list with every current brand from the brand source, giving up
+// after SearchRefreshTimeout so that a hung upstream cannot hold up later refreshes
+func (h *BrandsHandler) RefreshSearchIndex() error {
+	transID := transactionidutils.NewTransactionID()
+	ctx, cancel := context.WithTimeout(context.Background(), SearchRefreshTimeout)
+	defer cancel()
+	brands, err := h.source.GetBrands(ctx, transID)
+	if err != nil {
+		return err
+	}
+
+	var entries []searchEntry
+	for _, brand := range brands {
+		if brand.IsDeprecated {
+			continue
+		}
+		entries = append(entries, newSearchEntry(brand))
+	}
+	h.searchIndex.replace(entries)
+	h.brandList.replace(brands)
+	logger.WithTransactionID(transID).Infof("brand search index refreshed with %d brands", len(entries))
+	return nil
+}
+
+// RefreshSearchIndexEvery refreshes the search index straight away and then once every interval. It never returns.
+func (h *BrandsHandler) RefreshSearchIndexEvery(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := h.RefreshSearchIndex(); err != nil {
+			logger.WithError(err).Error("failed to refresh brand search index")
+		}
+		<-ticker.C
+	}
+}
+
+type searchIndex struct {
+	sync.RWMutex
+	entries []searchEntry
+	loaded  bool
+}
+
+// searchEntry is a brand with the lower cased labels it can be found by, prefLabel first
+type searchEntry struct {
+	thing  Thing
+	labels []string
+}
+
+type searchMatch struct {
+	entry   searchEntry
+	score   int
+	byAlias bool
+}
+
+func newSearchEntry(brand Brand) searchEntry {
+	entry := searchEntry{
+		thing: Thing{
+			ID:         brand.ID,
+			APIURL:     brand.APIURL,
+			DirectType: brand.DirectType,
+			PrefLabel:  brand.PrefLabel,
+		},
+		labels: []string{strings.ToLower(brand.PrefLabel)},
+	}
+	for _, alias := range brand.Aliases {
+		entry.labels = append(entry.labels, strings.ToLower(alias))
+	}
+	return entry
+}
+
+func (i *searchIndex) replace(entries []searchEntry) {
+	i.Lock()
+	defer i.Unlock()
+	i.entries = entries
+	i.loaded = true
+}
+
+// search ranks exact matches first, then prefixes of the label, prefixes of a word in the label, substrings
+// and finally fuzzy matches. Matches on prefLabel rank above matches on an alias with the same score.
+func (i *searchIndex) search(q string, limit int) ([]Thing, bool) {
+	i.RLock()
+	defer i.RUnlock()
+	if !i.loaded {
+		return nil, false
+	}
+
+	var matches []searchMatch
+	for _, entry := range i.entries {
+		best := searchMatch{entry: entry, score: noMatch}
+		for n, label := range entry.labels {
+			score := matchScore(q, label)
+			if score != noMatch && (best.score == noMatch || score < best.score) {
+				best.score = score
+				best.byAlias = n > 0
+			}
+		}
+		if best.score != noMatch {
+			matches = append(matches, best)
+		}
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		if matches[a].score != matches[b].score {
+			return matches[a].score < matches[b].score
+		}
+		if matches[a].byAlias != matches[b].byAlias {
+			return !matches[a].byAlias
+		}
+		if len(matches[a].entry.thing.PrefLabel) != len(matches[b].entry.thing.PrefLabel) {
+			return len(matches[a].entry.thing.PrefLabel) < len(matches[b].entry.thing.PrefLabel)
+		}
+		return matches[a].entry.thing.PrefLabel < matches[b].entry.thing.PrefLabel
+	})
+
+	results := []Thing{}
+	for _, match := range matches {
+		if len(results) == limit {
+			break
+		}
+		results = append(results, match.entry.thing)
+	}
+	return results, true
+}
+
+func matchScore(q string, label string) int {
+	switch {
+	case label == q:
+		return 0
+	case strings.HasPrefix(label, q):
+		return 1
+	case wordHasPrefix(label, q):
+		return 2
+	case strings.Contains(label, q):
+		return 3
+	}
+
+	maxEdits := 0
+	switch {
+	case len(q) >= 6:
+		maxEdits = 2
+	case len(q) >= 3:
+		maxEdits = 1
+	}
+	best := noMatch
+	for _, word := range append([]string{label}, strings.Fields(label)...) {
+		if d := prefixDistance(q, word); d <= maxEdits && (best == noMatch || d < best) {
+			best = d
+		}
+	}
+	if best == noMatch {
+		return noMatch
+	}
+	return 4 + best
+}
+
+func wordHasPrefix(label string, q string) bool {
+	for _, word := range strings.Fields(label) {
+		if strings.HasPrefix(word, q) {
+			return true
+		}
+	}
+	return false
+}
+
+// prefixDistance is the edit distance between q and the start of word that is as long as q,
+// so that a misspelt query still matches while it is being typed
+func prefixDistance(q string, word string) int {
+	a, b := []rune(q), []rune(word)
+	if len(b) > len(a) {
+		b = b[:len(a)]
+	}
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min3(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
@@ -0,0 +1,178 @@
+package brands
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchBrands(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name           string
+		url            string
+		expectedCode   int
+		expectedLabels []string
+	}
+
+	testCases := []testCase{
+		{
+			"Search Brands - Exact match before prefix matches",
+			"/brands/search?q=lex",
+			200,
+			[]string{"Lex", "Lexicon", "Lex Live"},
+		},
+		{
+			"Search Brands - Limits the number of results",
+			"/brands/search?q=Lex&limit=1",
+			200,
+			[]string{"Lex"},
+		},
+		{
+			"Search Brands - Matches the start of a word",
+			"/brands/search?q=live",
+			200,
+			[]string{"Lex Live"},
+		},
+		{
+			"Search Brands - Matches an alias",
+			"/brands/search?q=word%20p",
+			200,
+			[]string{"Lexicon"},
+		},
+		{
+			"Search Brands - Matches a misspelling",
+			"/brands/search?q=alphavile",
+			200,
+			[]string{"Alphaville"},
+		},
+		{
+			"Search Brands - Deprecated brands are not searchable",
+			"/brands/search?q=brics",
+			200,
+			nil,
+		},
+		{
+			"Search Brands - Missing query results in error",
+			"/brands/search?q=",
+			400,
+			nil,
+		},
+		{
+			"Search Brands - Invalid limit results in error",
+			"/brands/search?q=lex&limit=100",
+			400,
+			nil,
+		},
+	}
+
+	mockClient := mockHTTPClient{resp: brandSearchAsConcepts, statusCode: 200}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+	assert.NoError(t, bh.RefreshSearchIndex())
+
+	for _, test := range testCases {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.url, nil)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		if rr.Code != 200 {
+			continue
+		}
+		results := SearchResults{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &results), test.name+" failed: body is not search results!")
+		assert.Equal(t, test.expectedLabels, labelsOf(results.Brands), test.name+" failed: brands do not match!")
+	}
+}
+
+func TestSearchBrandsBeforeIndexIsLoaded(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	mockClient := mockHTTPClient{resp: brandSearchAsConcepts, statusCode: 200}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+
+	rr := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/brands/search?q=lex", nil)
+	req.Header.Set("X-Request-Id", testTransactionID)
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, 503, rr.Code)
+	assert.Equal(t, problemJSON(problemSearchNotReady, "brand search is not ready yet", "/brands/search?q=lex"), rr.Body.String())
+}
+
+func TestRefreshSearchIndexGivesUpOnHungSource(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	SearchRefreshTimeout = 10 * time.Millisecond
+	defer func() { SearchRefreshTimeout = time.Minute }()
+	bh := NewHandler(&hungBrandSource{})
+
+	refreshed := make(chan error)
+	go func() { refreshed <- bh.RefreshSearchIndex() }()
+
+	select {
+	case err := <-refreshed:
+		assert.Equal(t, context.DeadlineExceeded, err)
+	case <-time.After(time.Second):
+		t.Error("refresh of the search index did not give up on the hung brand source")
+	}
+}
+
+// hungBrandSource never answers a read of every brand, until the read is given up
+type hungBrandSource struct {
+	fakeBrandSource
+}
+
+func (h *hungBrandSource) GetBrands(ctx context.Context, transID string) ([]Brand, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+var brandSearchAsConcepts = `{
+	"concepts": [
+		{
+			"id": "http://www.ft.com/thing/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			"apiUrl": "http://api.ft.com/concepts/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			"type": "http://www.ft.com/ontology/product/Brand",
+			"prefLabel": "Lex"
+		},
+		{
+			"id": "http://www.ft.com/thing/e363dfb8-f6d9-4f2c-beba-5162b334272b",
+			"apiUrl": "http://api.ft.com/concepts/e363dfb8-f6d9-4f2c-beba-5162b334272b",
+			"type": "http://www.ft.com/ontology/product/Brand",
+			"prefLabel": "Lex Live"
+		},
+		{
+			"id": "http://www.ft.com/thing/0be232ac-841f-11e8-8f42-da24cd01f044",
+			"apiUrl": "http://api.ft.com/concepts/0be232ac-841f-11e8-8f42-da24cd01f044",
+			"type": "http://www.ft.com/ontology/product/Brand",
+			"prefLabel": "Lexicon",
+			"alternativeLabels": [
+				{"type": "http://www.w3.org/2008/05/skos-xl#altLabel", "value": "Word Play"}
+			]
+		},
+		{
+			"id": "http://www.ft.com/thing/89d15f70-640d-11e4-9803-0800200c9a66",
+			"apiUrl": "http://api.ft.com/concepts/89d15f70-640d-11e4-9803-0800200c9a66",
+			"type": "http://www.ft.com/ontology/product/Brand",
+			"prefLabel": "Alphaville"
+		},
+		{
+			"id": "http://www.ft.com/thing/1b9c4d24-7fd5-41fa-9b7d-f4f5d5b15fb4",
+			"apiUrl": "http://api.ft.com/concepts/1b9c4d24-7fd5-41fa-9b7d-f4f5d5b15fb4",
+			"type": "http://www.ft.com/ontology/product/Brand",
+			"prefLabel": "Beyond Brics",
+			"isDeprecated": true
+		}
+	]
+}`
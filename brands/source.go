@@ -0,0 +1,21 @@
+package brands
+
+import (
+	"context"
+
+	fthealth "github.com/Financial-Times/go-fthealth/v1_1"
+)
+
+// BrandSource is where BrandsHandler reads brands from. Reads are given up once ctx is done.
+type BrandSource interface {
+	// GetBrand returns the brand with the uuid, and the canonical uuid of the brand which differs from uuid when
+	// it is not the canonical one. A concept that is not a brand is not found, but brand is then a Thing with the
+	// DirectType the concept does have, where the source knows it.
+	GetBrand(ctx context.Context, UUID string, transID string) (brand Brand, canonicalUUID string, found bool, err error)
+	// GetBrands returns every canonical brand, including deprecated ones, with its aliases but without relationships
+	GetBrands(ctx context.Context, transID string) ([]Brand, error)
+	// GetBrandUUIDByIdentifier returns the canonical uuid of the brand with an alternative identifier,
+	// authority is one of the short names in identifierAuthorities
+	GetBrandUUIDByIdentifier(ctx context.Context, authority string, identifierValue string, transID string) (canonicalUUID string, found bool, err error)
+	HealthCheck() fthealth.Check
+}
@@ -0,0 +1,57 @@
+package brands
+
+import (
+	"context"
+	"io"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/Financial-Times/public-brands-api/v4/brands"
+
+// propagator reads the W3C trace context of incoming requests and passes it on to the upstream apis. Spans are
+// only recorded once a tracer provider has been set with otel.SetTracerProvider, the context is passed on either way.
+var propagator = propagation.TraceContext{}
+
+// NewTracerProvider batches the spans of serviceName and sends them to exporter. It should be shut down before
+// the service exits, so that the spans still in the batch are exported.
+func NewTracerProvider(exporter sdktrace.SpanExporter, serviceName string) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+	)
+}
+
+// NewOTLPExporter sends spans to the OpenTelemetry collector at endpoint as OTLP/HTTP, e.g. http://localhost:4318
+func NewOTLPExporter(ctx context.Context, endpoint string) (sdktrace.SpanExporter, error) {
+	return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint+"/v1/traces"))
+}
+
+// NewStdoutExporter writes every span as json, which is mostly useful when running locally
+func NewStdoutExporter(out io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(out))
+}
+
+// startSpan starts a span as a child of the span in ctx, or as the root of a new trace, and returns a context
+// carrying it for the spans and upstream requests that follow
+func startSpan(ctx context.Context, name string, kind trace.SpanKind, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(kind), trace.WithAttributes(attributes...))
+}
+
+// recordSpanError marks the span as failed with err, if there is one
+func recordSpanError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,180 @@
+package brands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestGetBrandTracing(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+
+	type testCase struct {
+		name                  string
+		clientCode            int
+		clientBody            string
+		expectedSpans         []string
+		expectedCanonicalUUID string
+		expectedResult        string
+	}
+
+	testCases := []testCase{
+		{
+			"Tracing - Brand is found",
+			200,
+			getBasicBrandAsConcept,
+			[]string{"HTTP GET", "mapBrand", "GetBrand"},
+			"2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+			"found",
+		},
+		{
+			"Tracing - Brand is redirected",
+			200,
+			getRedirectedBrand,
+			[]string{"HTTP GET", "mapBrand", "GetBrand"},
+			"d44db9cd-276d-4035-873f-39a9d8226641",
+			"redirect",
+		},
+		{
+			"Tracing - Brand is not found",
+			404,
+			"",
+			[]string{"HTTP GET", "GetBrand"},
+			"",
+			"not-found",
+		},
+	}
+
+	recorder, restore := recordSpans()
+	defer restore()
+
+	for _, test := range testCases {
+		recorder.Reset()
+		client := &recordingHTTPClient{mockHTTPClient: mockHTTPClient{resp: test.clientBody, statusCode: test.clientCode}}
+		router := mux.NewRouter()
+		bh := NewHandler(NewConceptsAPISource(client, "localhost:8080/concepts", "localhost:8080/concepts"))
+		bh.RegisterHandlers(router)
+
+		req, _ := http.NewRequest("GET", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		router.ServeHTTP(httptest.NewRecorder(), req)
+
+		var names []string
+		spans := map[string]sdktrace.ReadOnlySpan{}
+		for _, span := range recorder.Ended() {
+			names = append(names, span.Name())
+			spans[span.Name()] = span
+			assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String(), test.name+" failed: trace ids do not match!")
+		}
+		assert.Equal(t, test.expectedSpans, names, test.name+" failed: spans do not match!")
+
+		server := spans["GetBrand"]
+		assert.Equal(t, trace.SpanKindServer, server.SpanKind(), test.name+" failed: span kinds do not match!")
+		assert.Equal(t, "00f067aa0ba902b7", server.Parent().SpanID().String(), test.name+" failed: the request span is not a child of the caller!")
+		attributes := spanAttributes(server)
+		assert.Equal(t, "2d3e16e0-61cb-4322-8aff-3b01c59f4daa", attributes["brand.uuid"], test.name+" failed: uuids do not match!")
+		assert.Equal(t, test.expectedCanonicalUUID, attributes["brand.canonical_uuid"], test.name+" failed: canonical uuids do not match!")
+		assert.Equal(t, test.expectedResult, attributes["brand.result"], test.name+" failed: results do not match!")
+
+		upstream := spans["HTTP GET"]
+		assert.Equal(t, trace.SpanKindClient, upstream.SpanKind(), test.name+" failed: span kinds do not match!")
+		assert.Equal(t, server.SpanContext().SpanID(), upstream.Parent().SpanID(), test.name+" failed: the upstream span is not a child of the request span!")
+		assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-"+upstream.SpanContext().SpanID().String()+"-01", client.requestHeader.Get("traceparent"), test.name+" failed: traceparent was not propagated!")
+	}
+}
+
+func TestGetBrandTracingRecordsErrors(t *testing.T) {
+	recorder, restore := recordSpans()
+	defer restore()
+
+	client := &recordingHTTPClient{mockHTTPClient: mockHTTPClient{statusCode: 503}}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(client, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+
+	req, _ := http.NewRequest("GET", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", nil)
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 2)
+	for _, span := range spans {
+		assert.Equal(t, codes.Error, span.Status().Code, span.Name()+" failed: status codes do not match!")
+	}
+	assert.Equal(t, outcomeError, spanAttributes(spans[len(spans)-1])["brand.result"])
+}
+
+func TestGetBrandNotTracedWithoutTracerProvider(t *testing.T) {
+	client := &recordingHTTPClient{mockHTTPClient: mockHTTPClient{resp: getBasicBrandAsConcept, statusCode: 200}}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(client, "localhost:8080/concepts", "localhost:8080/concepts"))
+	bh.RegisterHandlers(router)
+
+	req, _ := http.NewRequest("GET", "/brands/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01", client.requestHeader.Get("traceparent"))
+}
+
+func TestStdoutExporter(t *testing.T) {
+	out := &bytes.Buffer{}
+	exporter, err := NewStdoutExporter(out)
+	assert.NoError(t, err)
+	provider := NewTracerProvider(exporter, "public-brands-api")
+	_, span := provider.Tracer(tracerName).Start(context.Background(), "GetBrand")
+	span.End()
+	assert.NoError(t, provider.Shutdown(context.Background()))
+
+	var exported struct{ Name string }
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &exported))
+	assert.Equal(t, "GetBrand", exported.Name)
+	assert.Contains(t, out.String(), `"public-brands-api"`)
+}
+
+// recordSpans sets a tracer provider that records every span in the order they ended, and returns a func that
+// sets a tracer provider that does not record spans again
+func recordSpans() (*tracetest.SpanRecorder, func()) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder, func() { otel.SetTracerProvider(noop.NewTracerProvider()) }
+}
+
+func spanAttributes(span sdktrace.ReadOnlySpan) map[string]string {
+	attributes := map[string]string{}
+	for _, kv := range span.Attributes() {
+		attributes[string(kv.Key)] = kv.Value.Emit()
+	}
+	return attributes
+}
+
+// recordingHTTPClient remembers the headers and body of the last request made with it
+type recordingHTTPClient struct {
+	mockHTTPClient
+	requestHeader http.Header
+	requestBody   string
+}
+
+func (c *recordingHTTPClient) Do(req *http.Request) (*http.Response, error) {
+	c.requestHeader = req.Header
+	if req.Body != nil {
+		body, _ := ioutil.ReadAll(req.Body)
+		c.requestBody = string(body)
+	}
+	return c.mockHTTPClient.Do(req)
+}
@@ -0,0 +1,147 @@
+package brands
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	logger "github.com/Financial-Times/go-logger"
+	transactionidutils "github.com/Financial-Times/transactionid-utils-go"
+	"github.com/gorilla/mux"
+)
+
+// GetBrandTree returns the brand with its child brands nested down to the requested depth
+func (h *BrandsHandler) GetBrandTree(w http.ResponseWriter, r *http.Request) {
+	UUID := mux.Vars(r)["uuid"]
+	transID := transactionidutils.GetTransactionIDFromRequest(r)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", CacheControlHeader)
+
+	depth := MaxTreeDepth
+	if d := r.URL.Query().Get("depth"); d != "" {
+		var err error
+		if depth, err = strconv.Atoi(d); err != nil || depth < 0 || depth > MaxTreeDepth {
+			writeProblem(w, r, problemInvalidRequest, fmt.Sprintf("depth must be a number between 0 and %d", MaxTreeDepth))
+			return
+		}
+	}
+
+	brand, ok := h.resolveBrand(w, r, UUID, transID)
+	if !ok {
+		return
+	}
+
+	tree, err := h.buildBrandTree(r.Context(), brand, depth, map[string]bool{}, transID)
+	if err != nil {
+		writeSourceError(w, r, err, "failed to return brand tree")
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	if err = json.NewEncoder(w).Encode(tree); err != nil {
+		logger.WithError(err).WithTransactionID(transID).WithUUID(UUID).Error("brand tree could not be marshaled")
+	}
+}
+
+// buildBrandTree expands the children of brand until depth is exhausted, one level at a time. The children of a
+// level are requested from the brand source at the same time, by at most TreeConcurrency requests.
+// Brands already in the tree are included again as leaves so that a cycle in the narrower relationships cannot recurse forever.
+func (h *BrandsHandler) buildBrandTree(ctx context.Context, brand Brand, depth int, visited map[string]bool, transID string) (BrandTreeNode, error) {
+	root := BrandTreeNode{Thing: brand.Thing}
+	visited[uuidFromID(brand.ID)] = true
+	level := []treeExpansion{{node: &root, brand: brand}}
+
+	for ; depth > 0 && len(level) > 0; depth-- {
+		var pending []*BrandTreeNode
+		for _, parent := range level {
+			parent.node.Children = make([]BrandTreeNode, len(parent.brand.Children))
+			for i, child := range parent.brand.Children {
+				parent.node.Children[i] = BrandTreeNode{Thing: child}
+				childUUID := uuidFromID(child.ID)
+				if visited[childUUID] {
+					logger.WithTransactionID(transID).WithUUID(childUUID).Warn("brand appears more than once in the tree, not expanding it again")
+					continue
+				}
+				visited[childUUID] = true
+				if depth > 1 {
+					pending = append(pending, &parent.node.Children[i])
+				}
+			}
+		}
+
+		children, err := h.getTreeChildren(ctx, pending, transID)
+		if err != nil {
+			return root, err
+		}
+		level = level[:0]
+		for i, child := range children {
+			if child.found {
+				pending[i].Thing = child.brand.Thing
+				visited[uuidFromID(child.brand.ID)] = true
+				level = append(level, treeExpansion{node: pending[i], brand: child.brand})
+			}
+		}
+	}
+	return root, nil
+}
+
+// treeExpansion is a brand whose children are still to be added to its node of the tree
+type treeExpansion struct {
+	node  *BrandTreeNode
+	brand Brand
+}
+
+type treeChild struct {
+	brand Brand
+	found bool
+}
+
+// getTreeChildren requests the brand of each node from the brand source using at most TreeConcurrency requests
+// at a time, giving up on all of them after the first error
+func (h *BrandsHandler) getTreeChildren(ctx context.Context, nodes []*BrandTreeNode, transID string) ([]treeChild, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	children := make([]treeChild, len(nodes))
+	errs := make([]error, len(nodes))
+	var wg sync.WaitGroup
+	jobs := make(chan int)
+	workers := TreeConcurrency
+	if workers > len(nodes) {
+		workers = len(nodes)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				brand, _, found, err := h.source.GetBrand(ctx, uuidFromID(nodes[i].ID), transID)
+				if err != nil {
+					cancel()
+				}
+				children[i], errs[i] = treeChild{brand: brand, found: found}, err
+			}
+		}()
+	}
+
+	for i := range nodes {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && !isContextError(err) {
+			return nil, err
+		}
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return children, nil
+}
@@ -0,0 +1,259 @@
+package brands
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Financial-Times/go-logger"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBrandTree(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	MaxTreeDepth = 3
+	defer func() { MaxTreeDepth = 5 }()
+
+	type testCase struct {
+		name         string
+		url          string
+		expectedCode int
+		expectedTree string
+	}
+
+	testCases := []testCase{
+		{
+			"Get Brand Tree - Defaults to the maximum depth",
+			"/brands/" + rootBrandUUID + "/tree",
+			200,
+			`FT(Lex(Lex Live,Lex Video),Alphaville(FT))`,
+		},
+		{
+			"Get Brand Tree - Stops at the requested depth",
+			"/brands/" + rootBrandUUID + "/tree?depth=1",
+			200,
+			`FT(Lex,Alphaville)`,
+		},
+		{
+			"Get Brand Tree - Depth of zero is only the brand",
+			"/brands/" + rootBrandUUID + "/tree?depth=0",
+			200,
+			`FT`,
+		},
+		{
+			"Get Brand Tree - Depth over the maximum results in error",
+			"/brands/" + rootBrandUUID + "/tree?depth=4",
+			400,
+			``,
+		},
+		{
+			"Get Brand Tree - Invalid UUID results in error",
+			"/brands/1234/tree",
+			400,
+			``,
+		},
+		{
+			"Get Brand Tree - Brand not found",
+			"/brands/99999999-1f0c-11e4-b0cb-b2227cce2b54/tree",
+			404,
+			``,
+		},
+	}
+
+	mockClient := mockRoutedHTTPClient{responses: brandTreeAsConcepts}
+	router := mux.NewRouter()
+	bh := NewHandler(NewConceptsAPISource(&mockClient, "http://localhost:8080", "http://localhost:8080"))
+	bh.RegisterHandlers(router)
+
+	for _, test := range testCases {
+		rr := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", test.url, nil)
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.expectedCode, rr.Code, test.name+" failed: status codes do not match!")
+		if rr.Code != 200 {
+			continue
+		}
+		tree := BrandTreeNode{}
+		assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &tree), test.name+" failed: body is not a brand tree!")
+		assert.Equal(t, test.expectedTree, treeLabels(tree), test.name+" failed: tree does not match!")
+	}
+}
+
+// treeLabels renders a tree as Label(Child,Child(GrandChild)) to keep expectations readable
+func treeLabels(node BrandTreeNode) string {
+	labels := node.PrefLabel
+	if len(node.Children) == 0 {
+		return labels
+	}
+	labels += "("
+	for i, child := range node.Children {
+		if i > 0 {
+			labels += ","
+		}
+		labels += treeLabels(child)
+	}
+	return labels + ")"
+}
+
+// mockRoutedHTTPClient answers each request with the body registered for its path, anything else is a 404
+type mockRoutedHTTPClient struct {
+	responses map[string]string
+}
+
+func (mrc *mockRoutedHTTPClient) Do(req *http.Request) (resp *http.Response, err error) {
+	body, ok := mrc.responses[req.URL.Path]
+	if !ok {
+		return &http.Response{Body: ioutil.NopCloser(bytes.NewReader(nil)), StatusCode: http.StatusNotFound}, nil
+	}
+	return &http.Response{Body: ioutil.NopCloser(bytes.NewReader([]byte(body))), StatusCode: http.StatusOK}, nil
+}
+
+const rootBrandUUID = "dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54"
+
+// brandTreeAsConcepts has Alphaville claiming FT as a child to check that cycles are not followed
+var brandTreeAsConcepts = map[string]string{
+	"/concepts/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54": `{
+		"id": "http://www.ft.com/thing/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54",
+		"apiUrl": "http://api.ft.com/concepts/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54",
+		"type": "http://www.ft.com/ontology/product/Brand",
+		"prefLabel": "FT",
+		"narrowerConcepts": [
+			{"concept": {"id": "http://www.ft.com/thing/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "apiUrl": "http://api.ft.com/concepts/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "Lex"}},
+			{"concept": {"id": "http://www.ft.com/thing/89d15f70-640d-11e4-9803-0800200c9a66", "apiUrl": "http://api.ft.com/concepts/89d15f70-640d-11e4-9803-0800200c9a66", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "Alphaville"}}
+		]
+	}`,
+	"/concepts/2d3e16e0-61cb-4322-8aff-3b01c59f4daa": `{
+		"id": "http://www.ft.com/thing/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+		"apiUrl": "http://api.ft.com/concepts/2d3e16e0-61cb-4322-8aff-3b01c59f4daa",
+		"type": "http://www.ft.com/ontology/product/Brand",
+		"prefLabel": "Lex",
+		"broaderConcepts": [
+			{"concept": {"id": "http://www.ft.com/thing/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54", "apiUrl": "http://api.ft.com/concepts/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "FT"}}
+		],
+		"narrowerConcepts": [
+			{"concept": {"id": "http://www.ft.com/thing/e363dfb8-f6d9-4f2c-beba-5162b334272b", "apiUrl": "http://api.ft.com/concepts/e363dfb8-f6d9-4f2c-beba-5162b334272b", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "Lex Live"}},
+			{"concept": {"id": "http://www.ft.com/thing/0be232ac-841f-11e8-8f42-da24cd01f044", "apiUrl": "http://api.ft.com/concepts/0be232ac-841f-11e8-8f42-da24cd01f044", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "Lex Video"}}
+		]
+	}`,
+	"/concepts/89d15f70-640d-11e4-9803-0800200c9a66": `{
+		"id": "http://www.ft.com/thing/89d15f70-640d-11e4-9803-0800200c9a66",
+		"apiUrl": "http://api.ft.com/concepts/89d15f70-640d-11e4-9803-0800200c9a66",
+		"type": "http://www.ft.com/ontology/product/Brand",
+		"prefLabel": "Alphaville",
+		"broaderConcepts": [
+			{"concept": {"id": "http://www.ft.com/thing/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54", "apiUrl": "http://api.ft.com/concepts/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "FT"}}
+		],
+		"narrowerConcepts": [
+			{"concept": {"id": "http://www.ft.com/thing/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54", "apiUrl": "http://api.ft.com/concepts/dbb0bdae-1f0c-11e4-b0cb-b2227cce2b54", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "FT"}}
+		]
+	}`,
+	"/concepts/e363dfb8-f6d9-4f2c-beba-5162b334272b": `{
+		"id": "http://www.ft.com/thing/e363dfb8-f6d9-4f2c-beba-5162b334272b",
+		"apiUrl": "http://api.ft.com/concepts/e363dfb8-f6d9-4f2c-beba-5162b334272b",
+		"type": "http://www.ft.com/ontology/product/Brand",
+		"prefLabel": "Lex Live",
+		"broaderConcepts": [
+			{"concept": {"id": "http://www.ft.com/thing/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "apiUrl": "http://api.ft.com/concepts/2d3e16e0-61cb-4322-8aff-3b01c59f4daa", "type": "http://www.ft.com/ontology/product/Brand", "prefLabel": "Lex"}}
+		]
+	}`,
+}
+
+func TestBuildBrandTreeRequestsLevelConcurrently(t *testing.T) {
+	logger.InitLogger("test-service", "debug")
+	TreeConcurrency = 4
+	defer func() { TreeConcurrency = 8 }()
+
+	type testCase struct {
+		name          string
+		failingUUID   string
+		expectedCalls int
+		expectedErr   error
+	}
+
+	testCases := []testCase{
+		{
+			"Build Brand Tree - Children of every level are requested",
+			"",
+			20 + 20*2,
+			nil,
+		},
+		{
+			"Build Brand Tree - Failure of one child fails the tree",
+			wideTreeUUID(7),
+			-1,
+			errors.New("upstream failed"),
+		},
+	}
+
+	for _, test := range testCases {
+		source := &wideBrandSource{failingUUID: test.failingUUID}
+		bh := NewHandler(source)
+		root, _, _, _ := source.GetBrand(context.Background(), rootBrandUUID, "tid_test")
+		source.calls = 0
+
+		tree, err := bh.buildBrandTree(context.Background(), root, 3, map[string]bool{}, "tid_test")
+
+		assert.Equal(t, test.expectedErr, err, test.name+" failed: errors do not match!")
+		assert.True(t, source.maxActive <= TreeConcurrency, test.name+" failed: too many concurrent requests!")
+		if test.expectedErr == nil {
+			assert.Equal(t, test.expectedCalls, source.calls, test.name+" failed: upstream calls do not match!")
+			assert.Len(t, tree.Children, 20, test.name+" failed: children do not match!")
+			assert.Len(t, tree.Children[19].Children, 2, test.name+" failed: grandchildren do not match!")
+		}
+	}
+}
+
+func wideTreeUUID(i int) string {
+	return fmt.Sprintf("%08d-0000-4000-8000-000000000000", i)
+}
+
+// wideBrandSource has the root brand with 20 children, each of which has 2 children of its own, and fails to read
+// the brand with failingUUID. It records the most reads that were in flight at the same time.
+type wideBrandSource struct {
+	fakeBrandSource
+	sync.Mutex
+	failingUUID string
+	active      int
+	maxActive   int
+}
+
+func (w *wideBrandSource) GetBrand(ctx context.Context, UUID string, transID string) (Brand, string, bool, error) {
+	w.Lock()
+	w.calls++
+	w.active++
+	if w.active > w.maxActive {
+		w.maxActive = w.active
+	}
+	w.Unlock()
+	time.Sleep(time.Millisecond)
+	defer func() {
+		w.Lock()
+		w.active--
+		w.Unlock()
+	}()
+
+	if UUID == w.failingUUID {
+		return Brand{}, "", false, errors.New("upstream failed")
+	}
+	brand := Brand{Thing: Thing{ID: thingsApiUrl + UUID}}
+	switch {
+	case UUID == rootBrandUUID:
+		for i := 1; i <= 20; i++ {
+			brand.Children = append(brand.Children, Thing{ID: thingsApiUrl + wideTreeUUID(i)})
+		}
+	case strings.HasSuffix(UUID, "-000000000000"):
+		brand.Children = []Thing{{ID: thingsApiUrl + UUID[:35] + "1"}, {ID: thingsApiUrl + UUID[:35] + "2"}}
+	}
+	return brand, UUID, true, nil
+}
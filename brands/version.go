@@ -0,0 +1,45 @@
+package brands
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultVersion = 1
+	latestVersion  = 2
+)
+
+// requestedVersion is the version of the brand response asked for, either with the version query
+// parameter or as a version parameter on the json media type in the Accept header, e.g.
+// Accept: application/json; version=2. The query parameter wins when both are given.
+func requestedVersion(r *http.Request) (int, error) {
+	if v := r.URL.Query().Get("version"); v != "" {
+		return parseVersion(v)
+	}
+
+	for _, mediaRange := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		if mediaType != "application/json" && mediaType != "application/*" && mediaType != "*/*" {
+			continue
+		}
+		if v, ok := params["version"]; ok {
+			return parseVersion(v)
+		}
+	}
+	return defaultVersion, nil
+}
+
+func parseVersion(v string) (int, error) {
+	version, err := strconv.Atoi(v)
+	if err != nil || version < defaultVersion || version > latestVersion {
+		return 0, fmt.Errorf("version must be a number between %d and %d", defaultVersion, latestVersion)
+	}
+	return version, nil
+}